@@ -0,0 +1,87 @@
+package asset_storage
+
+import (
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"sync"
+)
+
+// Storage stores the uploaded files, like the posters of the films, under a name which may contain slashes
+type Storage interface {
+	Save(name string, data []byte) error
+	// RemoveAll removes all the files whose name starts with the given directory
+	RemoveAll(directory string) error
+	// URL returns the url at which a stored file is served
+	URL(name string) string
+}
+
+// LocalStorage stores the files in a directory of the server, served at URLPrefix by static_serve
+type LocalStorage struct {
+	Dir       string
+	URLPrefix string
+}
+
+var (
+	defaultStorage     Storage
+	defaultStorageOnce sync.Once
+)
+
+// GetStorage returns the storage configured with the ASSETS_DIR and ASSETS_URL_PREFIX environment variables,
+// which are "./www/assets" and "/assets" by default
+func GetStorage() Storage {
+	defaultStorageOnce.Do(func() {
+		defaultStorage = NewLocalStorage(LocalDir(), URLPrefix())
+	})
+	return defaultStorage
+}
+
+func LocalDir() string {
+	if dir := os.Getenv("ASSETS_DIR"); len(dir) > 0 {
+		return dir
+	}
+	return "./www/assets"
+}
+
+func URLPrefix() string {
+	if prefix := os.Getenv("ASSETS_URL_PREFIX"); len(prefix) > 0 {
+		return "/" + strings.Trim(prefix, "/")
+	}
+	return "/assets"
+}
+
+func NewLocalStorage(dir string, urlPrefix string) *LocalStorage {
+	return &LocalStorage{Dir: dir, URLPrefix: urlPrefix}
+}
+
+// path returns the path of a stored file, names going out of the storage directory being kept inside it
+func (storage *LocalStorage) path(name string) string {
+	return filepath.Join(storage.Dir, filepath.FromSlash(path.Clean("/"+name)))
+}
+
+func (storage *LocalStorage) Save(name string, data []byte) error {
+	filePath := storage.path(name)
+	if err := os.MkdirAll(filepath.Dir(filePath), 0755); err != nil {
+		return err
+	}
+
+	// The file is written aside then renamed, so that it is never served half written
+	tmpPath := filePath + ".tmp"
+	if err := os.WriteFile(tmpPath, data, 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmpPath, filePath)
+}
+
+func (storage *LocalStorage) RemoveAll(directory string) error {
+	dirPath := storage.path(directory)
+	if filepath.Clean(dirPath) == filepath.Clean(storage.Dir) {
+		return nil
+	}
+	return os.RemoveAll(dirPath)
+}
+
+func (storage *LocalStorage) URL(name string) string {
+	return storage.URLPrefix + path.Clean("/"+name)
+}
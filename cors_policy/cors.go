@@ -0,0 +1,152 @@
+package cors_policy
+
+import (
+	"github.com/gin-gonic/gin"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// Policy tells which cross-origin requests the browsers may send
+type Policy struct {
+	// AllowedOrigins are the origins allowed to send requests, "*" allowing all of them
+	AllowedOrigins   []string
+	AllowedMethods   []string
+	AllowedHeaders   []string
+	ExposedHeaders   []string
+	AllowCredentials bool
+	// MaxAge is the number of seconds during which the browsers may cache the answer to a preflight request
+	MaxAge int
+}
+
+// exposedHeaders are the headers set by the API which the browsers may read
+var exposedHeaders = []string{"Location", "RateLimit-Limit", "RateLimit-Remaining", "RateLimit-Reset", "Retry-After"}
+
+// PublicPolicy returns the policy of the read requests, allowing all the origins by default. Its origins are set with
+// the CORS_PUBLIC_ORIGINS environment variable, a list separated by commas.
+func PublicPolicy() Policy {
+	return Policy{
+		AllowedOrigins: envList("CORS_PUBLIC_ORIGINS", []string{"*"}),
+		AllowedMethods: []string{http.MethodGet, http.MethodHead},
+		AllowedHeaders: envList("CORS_PUBLIC_HEADERS", []string{"Accept", "Accept-Language", "Authorization", "Content-Type"}),
+		ExposedHeaders: exposedHeaders,
+		MaxAge:         envInt("CORS_MAX_AGE", 600),
+	}
+}
+
+// AdminPolicy returns the policy of the write requests and of the admin routes, allowing no origin by default.
+// Its origins are set with the CORS_ADMIN_ORIGINS environment variable, and CORS_ADMIN_CREDENTIALS tells whether the
+// browsers may send their cookies.
+func AdminPolicy() Policy {
+	return Policy{
+		AllowedOrigins:   envList("CORS_ADMIN_ORIGINS", []string{}),
+		AllowedMethods:   []string{http.MethodGet, http.MethodHead, http.MethodPost, http.MethodPut, http.MethodPatch, http.MethodDelete},
+		AllowedHeaders:   envList("CORS_ADMIN_HEADERS", []string{"Accept", "Accept-Language", "Authorization", "Content-Type"}),
+		ExposedHeaders:   exposedHeaders,
+		AllowCredentials: os.Getenv("CORS_ADMIN_CREDENTIALS") == "true",
+		MaxAge:           envInt("CORS_MAX_AGE", 600),
+	}
+}
+
+// Middleware applies the admin policy to the write requests and to the routes under adminPrefix, and the public
+// policy to the others. It must be used by the router rather than by a group, so that it answers the preflight
+// requests, which match no route.
+func Middleware(public Policy, admin Policy, adminPrefix string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		origin := c.GetHeader("Origin")
+		if len(origin) == 0 {
+			c.Next()
+			return
+		}
+
+		// The method of a preflight request is the one of the request it is sent before
+		method := c.Request.Method
+		preflight := method == http.MethodOptions && len(c.GetHeader("Access-Control-Request-Method")) > 0
+		if preflight {
+			method = strings.ToUpper(c.GetHeader("Access-Control-Request-Method"))
+		}
+
+		policy := public
+		if strings.HasPrefix(c.Request.URL.Path, adminPrefix) || (method != http.MethodGet && method != http.MethodHead) {
+			policy = admin
+		}
+
+		c.Writer.Header().Add("Vary", "Origin")
+		allowed := policy.allowsOrigin(origin)
+
+		if preflight {
+			if !allowed || !containsFold(policy.AllowedMethods, method) {
+				c.IndentedJSON(http.StatusForbidden, gin.H{"message": "Cross-origin request denied"})
+				c.Abort()
+				return
+			}
+
+			policy.setOriginHeaders(c, origin)
+			c.Header("Access-Control-Allow-Methods", strings.Join(policy.AllowedMethods, ", "))
+			c.Header("Access-Control-Allow-Headers", strings.Join(policy.AllowedHeaders, ", "))
+			c.Header("Access-Control-Max-Age", strconv.Itoa(policy.MaxAge))
+			c.AbortWithStatus(http.StatusNoContent)
+			return
+		}
+
+		// The disallowed requests are still handled, but the browsers won't let the page read the responses
+		if allowed {
+			policy.setOriginHeaders(c, origin)
+			if len(policy.ExposedHeaders) > 0 {
+				c.Header("Access-Control-Expose-Headers", strings.Join(policy.ExposedHeaders, ", "))
+			}
+		}
+
+		c.Next()
+	}
+}
+
+func (policy Policy) allowsOrigin(origin string) bool {
+	return containsFold(policy.AllowedOrigins, "*") || containsFold(policy.AllowedOrigins, origin)
+}
+
+// setOriginHeaders allows the origin, which is echoed rather than "*" when credentials are allowed since browsers
+// reject "*" with credentials
+func (policy Policy) setOriginHeaders(c *gin.Context, origin string) {
+	if policy.AllowCredentials {
+		c.Header("Access-Control-Allow-Origin", origin)
+		c.Header("Access-Control-Allow-Credentials", "true")
+	} else if containsFold(policy.AllowedOrigins, "*") {
+		c.Header("Access-Control-Allow-Origin", "*")
+	} else {
+		c.Header("Access-Control-Allow-Origin", origin)
+	}
+}
+
+func containsFold(values []string, value string) bool {
+	for _, v := range values {
+		if strings.EqualFold(v, value) {
+			return true
+		}
+	}
+	return false
+}
+
+// envList returns the values of an environment variable separated by commas, or defaultValues if it is not set
+func envList(name string, defaultValues []string) []string {
+	value, exists := os.LookupEnv(name)
+	if !exists {
+		return defaultValues
+	}
+
+	values := []string{}
+	for _, v := range strings.Split(value, ",") {
+		if v = strings.TrimRight(strings.TrimSpace(v), "/"); len(v) > 0 {
+			values = append(values, v)
+		}
+	}
+	return values
+}
+
+func envInt(name string, defaultValue int) int {
+	if value, err := strconv.Atoi(os.Getenv(name)); err == nil && value >= 0 {
+		return value
+	}
+	return defaultValue
+}
@@ -9,21 +9,39 @@ import (
 	"strconv"
 )
 
+// Actor is the actor facet of a person
 type Actor struct {
-	Id    primitive.ObjectID `bson:"_id,omitempty" json:"id,omitempty"`
-	Name  string             `json:"name,omitempty" bson:"name"`
-	Films []string           `json:"films" bson:"films"` // Films is the slice of the films the actor played in
+	Id     primitive.ObjectID `bson:"_id,omitempty" json:"id,omitempty"`
+	Slug   string             `json:"slug,omitempty" bson:"slug,omitempty"`
+	Name   string             `json:"name,omitempty" bson:"name"`
+	Films  []string           `json:"films" bson:"acted_films"` // Films is the slice of the films the actor played in
+	Facets []string           `json:"facets,omitempty" bson:"facets,omitempty"`
+}
+
+// MergeReq is the body of the merge requests, Duplicate is the id of the item merged into the one of the url
+type MergeReq struct {
+	Duplicate string `json:"duplicate"`
 }
 
 func InitActorApiRoutes(apiRoutes *gin.RouterGroup, client *mongo.Client) {
 	InitActorCollection(client)
+	InitRedirectCollection(client)
+	InitSlugHistoryCollection(client)
+	InitAuditCollection(client)
+	InitRevisionCollection(client)
 
 	actorRoutes := apiRoutes.Group("/actors")
+	actorRoutes.Use(ResolveSlug("actor"))
+	actorRoutes.Use(Audit("actor", actorColl))
 	actorRoutes.GET("/", GetActors)
-	actorRoutes.POST("/", PostActor)
-	actorRoutes.PATCH("/:id", UpdateActor)
-	actorRoutes.DELETE("/:id", DeleteActor)
+	actorRoutes.POST("/", RequireScope(writePeopleScope), PostActor)
+	actorRoutes.PATCH("/:id", RequireScope(writePeopleScope), UpdateActor)
+	actorRoutes.DELETE("/:id", RequireScope(writePeopleScope), DeleteActor)
 	actorRoutes.GET("/:id", GetActorById)
+	actorRoutes.GET("/:id/costars", GetActorCoStars)
+	actorRoutes.POST("/:id/merge", RequireScope(writePeopleScope), MergeActor)
+	actorRoutes.GET("/:id/awards", GetActorAwards)
+	initRevisionRoutes(actorRoutes, "person", writePeopleScope)
 }
 
 func GetActors(c *gin.Context) {
@@ -37,11 +55,6 @@ func GetActors(c *gin.Context) {
 }
 
 func PostActor(c *gin.Context) {
-	if !CheckAuthKey(c) {
-		c.IndentedJSON(http.StatusForbidden, gin.H{"message": "Authentication failed"})
-		return
-	}
-
 	var newActor Actor
 
 	if err := c.BindJSON(&newActor); err != nil {
@@ -53,6 +66,10 @@ func PostActor(c *gin.Context) {
 		return
 	}
 
+	if isDuplicatePerson(c, newActor.Name) {
+		return
+	}
+
 	newActor, err := AddActor(newActor)
 	if err != nil {
 		c.IndentedJSON(http.StatusBadRequest, gin.H{"message": err})
@@ -73,11 +90,6 @@ func PostActor(c *gin.Context) {
 }
 
 func UpdateActor(c *gin.Context) {
-	if !CheckAuthKey(c) {
-		c.IndentedJSON(http.StatusForbidden, gin.H{"message": "Authentication failed"})
-		return
-	}
-
 	var updateData Actor
 	idString := c.Param("id")
 
@@ -94,6 +106,15 @@ func UpdateActor(c *gin.Context) {
 		return
 	}
 
+	updateData.Slug = ""
+	updateData.Facets = nil
+	if len(updateData.Name) > 0 && updateData.Name != oldData.Name {
+		if updateData.Slug, err = renameSlug("actor", id, oldData.Slug, slugify(updateData.Name)); err != nil {
+			c.IndentedJSON(http.StatusInternalServerError, gin.H{"message": err.Error()})
+			return
+		}
+	}
+
 	result := UpdateActorById(idString, updateData)
 
 	removedFilms := difference(oldData.Films, updateData.Films)
@@ -122,11 +143,6 @@ func UpdateActor(c *gin.Context) {
 }
 
 func DeleteActor(c *gin.Context) {
-	if !CheckAuthKey(c) {
-		c.IndentedJSON(http.StatusForbidden, gin.H{"message": "Authentication failed"})
-		return
-	}
-
 	id, err := primitive.ObjectIDFromHex(c.Param("id"))
 	if err != nil {
 		c.IndentedJSON(http.StatusBadRequest, gin.H{"message": err.Error()})
@@ -135,7 +151,23 @@ func DeleteActor(c *gin.Context) {
 
 	oldActor := FindActor(bson.M{"_id": id})
 
-	result := DeleteItemById(actorColl, id.Hex())
+	// The person is put in the trash if it has no other facet, otherwise only the facet is removed
+	if len(oldActor.Facets) == 1 {
+		result, err := trashPerson(FindPerson(bson.M{"_id": id}))
+		if err != nil {
+			c.IndentedJSON(http.StatusInternalServerError, gin.H{"message": err.Error()})
+			return
+		}
+		if result == 0 {
+			c.IndentedJSON(http.StatusNotModified, gin.H{"message": "No actor with the specified id"})
+			return
+		}
+
+		c.IndentedJSON(http.StatusNoContent, result)
+		return
+	}
+
+	result := RemovePersonFacet(id.Hex(), actorFacet)
 
 	if result == 0 {
 		c.IndentedJSON(http.StatusNotModified, gin.H{"message": "No film with the specified id"})
@@ -149,6 +181,10 @@ func DeleteActor(c *gin.Context) {
 		}
 	}
 
+	if _, err := RemovePersonFromAwards("actor", id.Hex()); err != nil {
+		return
+	}
+
 	c.IndentedJSON(http.StatusNoContent, result)
 }
 
@@ -162,6 +198,14 @@ func GetActorById(c *gin.Context) {
 
 	actor := FindActor(bson.M{"_id": id})
 
+	// The actor may have been merged into another one
+	if actor.Name == "" {
+		if target := FindRedirectTarget("actor", id.Hex()); len(target) > 0 {
+			targetId, _ := primitive.ObjectIDFromHex(target)
+			actor = FindActor(bson.M{"_id": targetId})
+		}
+	}
+
 	if actor.Name != "" {
 		c.IndentedJSON(http.StatusOK, actor)
 		return
@@ -169,3 +213,41 @@ func GetActorById(c *gin.Context) {
 
 	c.IndentedJSON(http.StatusNotFound, gin.H{"message": "Actor not found"})
 }
+
+// MergeActor merges the duplicate actor given in the body into the actor of the url
+func MergeActor(c *gin.Context) {
+	var req MergeReq
+	if err := c.BindJSON(&req); err != nil {
+		c.IndentedJSON(http.StatusBadRequest, gin.H{"message": "JSON is invalid"})
+		return
+	}
+
+	id, err := primitive.ObjectIDFromHex(c.Param("id"))
+	duplicateId, duplicateErr := primitive.ObjectIDFromHex(req.Duplicate)
+	if err != nil || duplicateErr != nil || id == duplicateId {
+		c.IndentedJSON(http.StatusBadRequest, gin.H{"message": "Id is invalid"})
+		return
+	}
+
+	target := FindActor(bson.M{"_id": id})
+	duplicate := FindActor(bson.M{"_id": duplicateId})
+	if target.Id.IsZero() || duplicate.Id.IsZero() {
+		c.IndentedJSON(http.StatusNotFound, gin.H{"message": "Actor not found"})
+		return
+	}
+
+	duplicateDocument := findDocument(actorColl, req.Duplicate)
+	merged, err := MergeActors(target, duplicate)
+	if err != nil {
+		c.IndentedJSON(http.StatusInternalServerError, gin.H{"message": err.Error()})
+		return
+	}
+
+	// The Audit middleware records the change of the target, and the duplicate is deleted by the merge
+	if err = newAuditTrail(c).record(http.StatusOK, "actor", req.Duplicate, duplicateDocument, nil); err != nil {
+		c.IndentedJSON(http.StatusInternalServerError, gin.H{"message": err.Error()})
+		return
+	}
+
+	c.IndentedJSON(http.StatusOK, merged)
+}
@@ -2,7 +2,6 @@ package film_api
 
 import (
 	"context"
-	"filmflix/db_connection"
 	"fmt"
 	"github.com/gin-gonic/gin"
 	"go.mongodb.org/mongo-driver/bson"
@@ -13,14 +12,15 @@ import (
 
 var actorColl *mongo.Collection
 
+// InitActorCollection initializes the collection of the actors, which are the people having the actor facet
 func InitActorCollection(client *mongo.Client) {
-	actorColl = db_connection.GetCollection(client, "films", "actors")
+	InitPersonCollection(client)
 }
 
 func FindActors(filter bson.M, maxCount int) []Actor {
 	var results []Actor
 	limit := int64(maxCount)
-	cursor, err := actorColl.Find(context.TODO(), filter, &options.FindOptions{Limit: &limit, Sort: bson.D{{"title", 1}}})
+	cursor, err := actorColl.Find(context.TODO(), notDeleted(withFacet(filter, actorFacet)), &options.FindOptions{Limit: &limit, Sort: bson.D{{"title", 1}}})
 	if err != nil {
 		panic(err)
 	}
@@ -34,7 +34,7 @@ func FindActors(filter bson.M, maxCount int) []Actor {
 
 func FindActor(filter bson.M) Actor {
 	var actor Actor
-	err := actorColl.FindOne(context.TODO(), filter).Decode(&actor)
+	err := actorColl.FindOne(context.TODO(), notDeleted(withFacet(filter, actorFacet))).Decode(&actor)
 
 	if err == mongo.ErrNoDocuments {
 		fmt.Printf("No document was found\n")
@@ -49,23 +49,35 @@ func FindActor(filter bson.M) Actor {
 
 func AddActor(actor Actor) (Actor, error) {
 	actor.Id = primitive.NewObjectID()
+	actor.Slug = UniqueSlug("actor", slugify(actor.Name), actor.Id)
+	actor.Facets = []string{actorFacet}
+	actor.Films = nonNil(actor.Films)
 
 	_, err := actorColl.InsertOne(context.TODO(), actor)
+	// Another person may have taken the slug since it was chosen
+	if mongo.IsDuplicateKeyError(err) {
+		actor.Slug = UniqueSlug("actor", slugify(actor.Name), actor.Id)
+		_, err = actorColl.InsertOne(context.TODO(), actor)
+	}
 	if err != nil {
 		return Actor{}, nil
 	}
 
+	catalogueChanged("person", actor.Id.Hex())
+
 	return actor, nil
 }
 
 func UpdateActorById(idString string, data interface{}) int64 {
 	id, _ := primitive.ObjectIDFromHex(idString)
 
-	result, err := actorColl.UpdateOne(context.TODO(), bson.D{{"_id", id}}, bson.M{"$set": data})
+	result, err := actorColl.UpdateOne(context.TODO(), notDeleted(bson.M{"_id": id}), bson.M{"$set": data})
 	if err != nil {
 		panic(err)
 	}
 
+	catalogueChanged("person", idString)
+
 	return result.ModifiedCount
 }
 
@@ -78,6 +90,8 @@ func ReplaceActor(idString string, newDirector Film) int64 {
 		panic(err)
 	}
 
+	catalogueChanged("person", idString)
+
 	return result.ModifiedCount
 }
 
@@ -88,11 +102,13 @@ func AddFilmsToActor(idString string, films []string) (int64, error) {
 		return 0, err
 	}
 
-	result, err := actorColl.UpdateOne(context.TODO(), bson.D{{"_id", id}}, bson.M{"$push": bson.M{"films": bson.M{"$each": films}}})
+	result, err := actorColl.UpdateOne(context.TODO(), bson.D{{"_id", id}}, bson.M{"$push": bson.M{"acted_films": bson.M{"$each": films}}, "$addToSet": bson.M{"facets": actorFacet}})
 	if err != nil {
 		return 0, err
 	}
 
+	catalogueChanged("person", idString)
+
 	return result.ModifiedCount, nil
 }
 
@@ -103,11 +119,13 @@ func RemoveFilmsFromActor(idString string, films []string) (int64, error) {
 		return 0, err
 	}
 
-	result, err := actorColl.UpdateOne(context.TODO(), bson.M{"_id": id}, bson.M{"$pull": bson.M{"films": bson.M{"$in": films}}})
+	result, err := actorColl.UpdateOne(context.TODO(), bson.M{"_id": id}, bson.M{"$pull": bson.M{"acted_films": bson.M{"$in": films}}})
 	if err != nil {
 		return 0, err
 	}
 
+	catalogueChanged("person", idString)
+
 	return result.ModifiedCount, nil
 }
 
@@ -127,10 +145,23 @@ func AreActorsIdsValid(roles []Role) (bool, gin.H) {
 		i++
 	}
 
-	result := FindActors(bson.M{"_id": bson.M{"$in": actorsIds}}, len(actorsIds))
+	// Any person can be given a new facet by being credited on a film
+	result := FindPeople(bson.M{"_id": bson.M{"$in": actorsIds}}, len(actorsIds))
 
 	if len(result) != len(actorsIds) {
 		return false, gin.H{"message": "At least one actor id does not exists"}
 	}
 	return true, nil
 }
+
+// MergeActors merges the duplicate actor into the target one, like MergePeople since they are both people
+func MergeActors(target Actor, duplicate Actor) (Actor, error) {
+	targetPerson := FindPerson(bson.M{"_id": target.Id})
+	duplicatePerson := FindPerson(bson.M{"_id": duplicate.Id})
+
+	if _, err := MergePeople(targetPerson, duplicatePerson); err != nil {
+		return Actor{}, err
+	}
+
+	return FindActor(bson.M{"_id": target.Id}), nil
+}
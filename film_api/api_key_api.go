@@ -0,0 +1,104 @@
+package film_api
+
+import (
+	"fmt"
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// ApiKey is a key given to a client of the API, only its hash is stored. The key is made of a public prefix,
+// used to find it, and of a secret.
+type ApiKey struct {
+	Id         primitive.ObjectID `json:"id" bson:"_id,omitempty"`
+	Name       string             `json:"name" bson:"name"`
+	Prefix     string             `json:"prefix" bson:"prefix"`
+	Hash       string             `json:"-" bson:"hash"` // Hash is the hexadecimal SHA-256 hash of the whole key
+	Scopes     []string           `json:"scopes" bson:"scopes"`
+	CreatedAt  time.Time          `json:"created_at" bson:"created_at"`
+	ExpiresAt  *time.Time         `json:"expires_at,omitempty" bson:"expires_at,omitempty"` // ExpiresAt is nil for the keys which never expire
+	LastUsedAt *time.Time         `json:"last_used_at,omitempty" bson:"last_used_at,omitempty"`
+	RevokedAt  *time.Time         `json:"revoked_at,omitempty" bson:"revoked_at,omitempty"`
+}
+
+type ApiKeyReq struct {
+	Name      string     `json:"name"`
+	Scopes    []string   `json:"scopes"`
+	ExpiresAt *time.Time `json:"expires_at"`
+}
+
+func InitApiKeyApiRoutes(apiRoutes *gin.RouterGroup, client *mongo.Client) {
+	InitApiKeyCollection(client)
+
+	keyRoutes := apiRoutes.Group("/admin/keys", RequireScope(adminScope))
+	keyRoutes.GET("/", GetApiKeys)
+	keyRoutes.POST("/", PostApiKey)
+	keyRoutes.DELETE("/:id", RevokeApiKeyById)
+}
+
+func GetApiKeys(c *gin.Context) {
+	c.IndentedJSON(http.StatusOK, FindApiKeys(bson.M{}))
+}
+
+// PostApiKey creates an API key, the key itself is only sent in this response
+func PostApiKey(c *gin.Context) {
+	var req ApiKeyReq
+	if err := c.BindJSON(&req); err != nil {
+		c.IndentedJSON(http.StatusBadRequest, gin.H{"message": "JSON is invalid"})
+		return
+	}
+
+	if len(strings.TrimSpace(req.Name)) == 0 || len(req.Scopes) == 0 {
+		c.IndentedJSON(http.StatusBadRequest, gin.H{"message": "The name and the scopes are required"})
+		return
+	}
+	for i, scope := range req.Scopes {
+		if !containsString(apiKeyScopes, scope) {
+			c.IndentedJSON(http.StatusBadRequest, gin.H{"message": fmt.Sprintf("Scope no. %v is invalid", i)})
+			return
+		}
+	}
+	if req.ExpiresAt != nil && req.ExpiresAt.Before(time.Now()) {
+		c.IndentedJSON(http.StatusBadRequest, gin.H{"message": "The expiry date is in the past"})
+		return
+	}
+
+	secret, prefix, hash, err := generateApiKey()
+	if err != nil {
+		c.IndentedJSON(http.StatusInternalServerError, gin.H{"message": err.Error()})
+		return
+	}
+
+	key, err := AddApiKey(ApiKey{
+		Name:      strings.TrimSpace(req.Name),
+		Prefix:    prefix,
+		Hash:      hash,
+		Scopes:    req.Scopes,
+		CreatedAt: time.Now(),
+		ExpiresAt: req.ExpiresAt,
+	})
+	if err != nil {
+		c.IndentedJSON(http.StatusInternalServerError, gin.H{"message": err.Error()})
+		return
+	}
+
+	c.IndentedJSON(http.StatusCreated, gin.H{"key": secret, "api_key": key})
+}
+
+func RevokeApiKeyById(c *gin.Context) {
+	result, err := RevokeApiKey(c.Param("id"))
+	if err != nil {
+		c.IndentedJSON(http.StatusBadRequest, gin.H{"message": "Id is invalid"})
+		return
+	}
+	if result == 0 {
+		c.IndentedJSON(http.StatusNotModified, gin.H{"message": "No active key with the specified id"})
+		return
+	}
+
+	c.IndentedJSON(http.StatusNoContent, result)
+}
@@ -0,0 +1,78 @@
+package film_api
+
+import (
+	"context"
+	"filmflix/db_connection"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"time"
+)
+
+var apiKeyColl *mongo.Collection
+
+func InitApiKeyCollection(client *mongo.Client) {
+	apiKeyColl = db_connection.GetCollection(client, "films", "api_keys")
+}
+
+// FindApiKeys retrieves the API keys, the most recent first
+func FindApiKeys(filter bson.M) []ApiKey {
+	results := []ApiKey{}
+	cursor, err := apiKeyColl.Find(context.TODO(), filter, options.Find().SetSort(bson.M{"created_at": -1}))
+	if err != nil {
+		panic(err)
+	}
+
+	if err = cursor.All(context.TODO(), &results); err != nil {
+		panic(err)
+	}
+
+	return results
+}
+
+func FindApiKey(filter bson.M) ApiKey {
+	var key ApiKey
+	err := apiKeyColl.FindOne(context.TODO(), filter).Decode(&key)
+
+	if err == mongo.ErrNoDocuments {
+		return key
+	}
+	if err != nil {
+		panic(err)
+	}
+
+	return key
+}
+
+func AddApiKey(key ApiKey) (ApiKey, error) {
+	key.Id = primitive.NewObjectID()
+
+	_, err := apiKeyColl.InsertOne(context.TODO(), key)
+	if err != nil {
+		return ApiKey{}, err
+	}
+
+	return key, nil
+}
+
+// RevokeApiKey revokes an API key and returns the number of revoked keys
+func RevokeApiKey(idString string) (int64, error) {
+	id, err := primitive.ObjectIDFromHex(idString)
+	if err != nil {
+		return 0, err
+	}
+
+	result, err := apiKeyColl.UpdateOne(context.TODO(), bson.M{"_id": id, "revoked_at": bson.M{"$exists": false}}, bson.M{"$set": bson.M{"revoked_at": time.Now()}})
+	if err != nil {
+		return 0, err
+	}
+
+	return result.ModifiedCount, nil
+}
+
+// TouchApiKey records that an API key has just been used
+func TouchApiKey(id primitive.ObjectID) error {
+	_, err := apiKeyColl.UpdateOne(context.TODO(), bson.M{"_id": id}, bson.M{"$set": bson.M{"last_used_at": time.Now()}})
+	return err
+}
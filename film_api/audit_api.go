@@ -0,0 +1,271 @@
+package film_api
+
+import (
+	"bytes"
+	"encoding/json"
+	"filmflix/request_id"
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"net/http"
+	"reflect"
+	"strconv"
+	"time"
+)
+
+// Actions of the audit entries
+const (
+	createAction  = "create"
+	updateAction  = "update"
+	deleteAction  = "delete"
+	restoreAction = "restore"
+)
+
+// AuditActor is who made a change: a user, or a client with an API key
+type AuditActor struct {
+	Kind string `json:"kind" bson:"kind"` // Kind is user or api_key
+	Id   string `json:"id,omitempty" bson:"id,omitempty"`
+	Name string `json:"name" bson:"name"`
+}
+
+// AuditEntry records a change of an entity of the catalogue
+type AuditEntry struct {
+	Id        primitive.ObjectID `json:"id" bson:"_id,omitempty"`
+	RequestId string             `json:"request_id" bson:"request_id"`
+	Time      time.Time          `json:"time" bson:"time"`
+	Actor     AuditActor         `json:"actor" bson:"actor"`
+	Method    string             `json:"method" bson:"method"`
+	Route     string             `json:"route" bson:"route"`
+	Path      string             `json:"path" bson:"path"`
+	Status    int                `json:"status" bson:"status"`
+	Entity    string             `json:"entity" bson:"entity"`
+	EntityId  string             `json:"entity_id" bson:"entity_id"`
+	Action    string             `json:"action" bson:"action"`
+	// Changes maps each changed field of the entity to its "before" and "after" values
+	Changes bson.M `json:"changes" bson:"changes"`
+}
+
+func InitAuditApiRoutes(apiRoutes *gin.RouterGroup, client *mongo.Client) {
+	InitAuditCollection(client)
+
+	apiRoutes.GET("/admin/audit", RequireScope(adminScope), GetAuditEntries)
+}
+
+// GetAuditEntries returns the audit entries, filtered by ?entity, ?entity_id, ?user (the id of a user or of an API
+// key), ?action, and by the ?from and ?to dates
+func GetAuditEntries(c *gin.Context) {
+	filter := bson.M{}
+	for param, field := range map[string]string{"entity": "entity", "entity_id": "entity_id", "user": "actor.id", "action": "action", "request_id": "request_id"} {
+		if value, exists := c.GetQuery(param); exists {
+			filter[field] = value
+		}
+	}
+
+	timeFilter := bson.M{}
+	if from, exists := c.GetQuery("from"); exists {
+		date, _, err := parseAuditTime(from)
+		if err != nil {
+			c.IndentedJSON(http.StatusBadRequest, gin.H{"message": "The from date is invalid"})
+			return
+		}
+		timeFilter["$gte"] = date
+	}
+	if to, exists := c.GetQuery("to"); exists {
+		date, isDay, err := parseAuditTime(to)
+		if err != nil {
+			c.IndentedJSON(http.StatusBadRequest, gin.H{"message": "The to date is invalid"})
+			return
+		}
+		// A day given as the end of the range is included
+		if isDay {
+			date = date.AddDate(0, 0, 1)
+		}
+		timeFilter["$lt"] = date
+	}
+	if len(timeFilter) > 0 {
+		filter["time"] = timeFilter
+	}
+
+	limit := 100
+	if limitStr, exists := c.GetQuery("limit"); exists {
+		var err error
+		limit, err = strconv.Atoi(limitStr)
+		if err != nil {
+			c.IndentedJSON(http.StatusBadRequest, gin.H{"message": "The limit is invalid"})
+			return
+		}
+	}
+
+	c.IndentedJSON(http.StatusOK, FindAuditEntries(filter, limit))
+}
+
+// Audit is a middleware recording the successful write requests of a group of routes in the audit log, and the
+// revisions of the films and of the people. The entity is the document of coll whose id is the :id parameter, or
+// the one created by the request.
+func Audit(entity string, coll *mongo.Collection) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if c.Request.Method == http.MethodGet || c.Request.Method == http.MethodHead || c.Request.Method == http.MethodOptions {
+			c.Next()
+			return
+		}
+
+		entityId := c.Param("id")
+		before := findDocument(coll, entityId)
+
+		// The id of a created entity is only known from the response
+		var writer *bodyWriter
+		if len(entityId) == 0 {
+			writer = &bodyWriter{ResponseWriter: c.Writer}
+			c.Writer = writer
+		}
+
+		c.Next()
+
+		status := c.Writer.Status()
+		if status < 200 || status >= 300 {
+			return
+		}
+
+		if writer != nil {
+			var created struct {
+				Id string `json:"id"`
+			}
+			if json.Unmarshal(writer.body.Bytes(), &created) != nil || len(created.Id) == 0 {
+				return
+			}
+			entityId = created.Id
+		}
+
+		after := findDocument(coll, entityId)
+
+		if err := newAuditTrail(c).record(status, entity, entityId, before, after); err != nil {
+			panic(err)
+		}
+	}
+}
+
+// auditTrail is what the audit entries of a request have in common. It is taken from the request while it is handled,
+// so that the changes made in the background once it is done are recorded too.
+type auditTrail struct {
+	requestId string
+	actor     AuditActor
+	method    string
+	route     string
+	path      string
+}
+
+func newAuditTrail(c *gin.Context) auditTrail {
+	return auditTrail{
+		requestId: request_id.Get(c),
+		actor:     auditActor(c),
+		method:    c.Request.Method,
+		route:     c.FullPath(),
+		path:      c.Request.URL.Path,
+	}
+}
+
+// record adds the audit entry of the change of an entity from the before document to the after one, and its revision
+func (trail auditTrail) record(status int, entity string, entityId string, before bson.M, after bson.M) error {
+	action := updateAction
+	switch {
+	case before == nil && after != nil:
+		action = createAction
+	case before != nil && (after == nil || isTrashed(after) && !isTrashed(before)):
+		action = deleteAction
+	case isTrashed(before) && !isTrashed(after):
+		action = restoreAction
+	}
+
+	entry, err := AddAuditEntry(AuditEntry{
+		RequestId: trail.requestId,
+		Time:      time.Now(),
+		Actor:     trail.actor,
+		Method:    trail.method,
+		Route:     trail.route,
+		Path:      trail.path,
+		Status:    status,
+		Entity:    entity,
+		EntityId:  entityId,
+		Action:    action,
+		Changes:   diffDocuments(before, after),
+	})
+	if err != nil {
+		return err
+	}
+
+	return recordRevision(entry, before, after)
+}
+
+// auditedLink wraps a function linking an entity of coll to films, so that the change of the entity is recorded in the
+// audit log along with the request which made it
+func auditedLink(c *gin.Context, entity string, coll *mongo.Collection, update func(string, []string) (int64, error)) func(string, []string) (int64, error) {
+	trail := newAuditTrail(c)
+	return func(id string, films []string) (int64, error) {
+		before := findDocument(coll, id)
+		count, err := update(id, films)
+		if err != nil || count == 0 {
+			return count, err
+		}
+		return count, trail.record(http.StatusOK, entity, id, before, findDocument(coll, id))
+	}
+}
+
+// bodyWriter keeps a copy of the body of the response
+type bodyWriter struct {
+	gin.ResponseWriter
+	body bytes.Buffer
+}
+
+func (writer *bodyWriter) Write(data []byte) (int, error) {
+	writer.body.Write(data)
+	return writer.ResponseWriter.Write(data)
+}
+
+// auditActor returns who is authenticated by the request, set by RequireScope or authenticateUser
+func auditActor(c *gin.Context) AuditActor {
+	if claims, exists := c.Get("claims"); exists {
+		return AuditActor{Kind: "user", Id: claims.(TokenClaims).Subject, Name: claims.(TokenClaims).Name}
+	}
+	if apiKey, exists := c.Get("api_key"); exists {
+		key := apiKey.(ApiKey)
+		if key.Id.IsZero() {
+			return AuditActor{Kind: "api_key", Name: key.Name}
+		}
+		return AuditActor{Kind: "api_key", Id: key.Id.Hex(), Name: key.Name}
+	}
+	if user, exists := c.Get("user"); exists {
+		return AuditActor{Kind: "user", Id: user.(User).Id.Hex(), Name: user.(User).Name}
+	}
+	return AuditActor{Kind: "anonymous"}
+}
+
+// diffDocuments returns the fields which are different in the two documents, either of them being nil if the entity
+// was created or deleted
+func diffDocuments(before bson.M, after bson.M) bson.M {
+	changes := bson.M{}
+	for field, value := range before {
+		if field == "_id" {
+			continue
+		}
+		if !reflect.DeepEqual(value, after[field]) {
+			changes[field] = bson.M{"before": value, "after": after[field]}
+		}
+	}
+	for field, value := range after {
+		if _, exists := before[field]; !exists && field != "_id" {
+			changes[field] = bson.M{"before": nil, "after": value}
+		}
+	}
+	return changes
+}
+
+// parseAuditTime parses a date of the audit filters, given either as a RFC 3339 time or as a day, and tells whether
+// it is a day
+func parseAuditTime(value string) (time.Time, bool, error) {
+	if date, err := time.Parse(time.RFC3339, value); err == nil {
+		return date, false, nil
+	}
+	date, err := time.Parse("2006-01-02", value)
+	return date, true, err
+}
@@ -0,0 +1,63 @@
+package film_api
+
+import (
+	"context"
+	"filmflix/db_connection"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+var auditColl *mongo.Collection
+
+func InitAuditCollection(client *mongo.Client) {
+	auditColl = db_connection.GetCollection(client, "films", "audit_log")
+}
+
+// FindAuditEntries retrieves the audit entries matching the filter, the most recent first
+func FindAuditEntries(filter bson.M, maxCount int) []AuditEntry {
+	results := []AuditEntry{}
+	limit := int64(maxCount)
+	cursor, err := auditColl.Find(context.TODO(), filter, &options.FindOptions{Limit: &limit, Sort: bson.M{"time": -1}})
+	if err != nil {
+		panic(err)
+	}
+
+	if err = cursor.All(context.TODO(), &results); err != nil {
+		panic(err)
+	}
+
+	return results
+}
+
+func AddAuditEntry(entry AuditEntry) (AuditEntry, error) {
+	entry.Id = primitive.NewObjectID()
+
+	_, err := auditColl.InsertOne(context.TODO(), entry)
+	if err != nil {
+		return AuditEntry{}, err
+	}
+
+	return entry, nil
+}
+
+// findDocument returns the raw document of a collection with the given id, or nil if there is none, so that it can be
+// compared field by field
+func findDocument(coll *mongo.Collection, idString string) bson.M {
+	id, err := primitive.ObjectIDFromHex(idString)
+	if err != nil {
+		return nil
+	}
+
+	var document bson.M
+	err = coll.FindOne(context.TODO(), bson.M{"_id": id}).Decode(&document)
+	if err == mongo.ErrNoDocuments {
+		return nil
+	}
+	if err != nil {
+		panic(err)
+	}
+
+	return document
+}
@@ -0,0 +1,201 @@
+package film_api
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"golang.org/x/crypto/bcrypt"
+	"net/http"
+	"time"
+)
+
+// refreshTokenDuration is the lifetime of the refresh tokens, after which the user must log in again
+const refreshTokenDuration = 30 * 24 * time.Hour
+
+// RefreshToken is given with an access token to get a new one once it expires. Only its hash is stored.
+type RefreshToken struct {
+	Id        primitive.ObjectID `json:"id" bson:"_id,omitempty"`
+	UserId    primitive.ObjectID `json:"user" bson:"user"`
+	Hash      string             `json:"-" bson:"hash"`
+	CreatedAt time.Time          `json:"created_at" bson:"created_at"`
+	ExpiresAt time.Time          `json:"expires_at" bson:"expires_at"`
+	RevokedAt *time.Time         `json:"revoked_at,omitempty" bson:"revoked_at,omitempty"`
+}
+
+// dummyPasswordHash is compared with the password given for an unknown user, so that the time of the answer doesn't
+// tell which names exist
+const dummyPasswordHash = "$2a$10$jUbZQZAlElktGykEOmF3AOlKSMnVH5xybFyQ8D2W60t4UhVJGJePu"
+
+type LoginReq struct {
+	Name     string `json:"name"`
+	Password string `json:"password"`
+}
+
+type RefreshReq struct {
+	RefreshToken string `json:"refresh_token"`
+}
+
+type RoleReq struct {
+	Role string `json:"role"`
+}
+
+func InitAuthApiRoutes(apiRoutes *gin.RouterGroup, client *mongo.Client) {
+	InitUserCollection(client)
+	InitRefreshTokenCollection(client)
+
+	authRoutes := apiRoutes.Group("/auth")
+	authRoutes.POST("/login", Login)
+	authRoutes.POST("/refresh", RefreshTokens)
+	authRoutes.POST("/logout", Logout)
+
+	apiRoutes.PUT("/admin/users/:id/role", RequireScope(adminScope), UpdateUserRole)
+}
+
+// Login checks the name and the password of a user and gives him an access token and a refresh token
+func Login(c *gin.Context) {
+	var req LoginReq
+	if err := c.BindJSON(&req); err != nil {
+		c.IndentedJSON(http.StatusBadRequest, gin.H{"message": "JSON is invalid"})
+		return
+	}
+
+	user := FindUser(bson.M{"name": req.Name})
+	passwordHash := user.PasswordHash
+	if user.Id.IsZero() {
+		passwordHash = dummyPasswordHash
+	}
+	if bcrypt.CompareHashAndPassword([]byte(passwordHash), []byte(req.Password)) != nil || user.Id.IsZero() {
+		c.IndentedJSON(http.StatusUnauthorized, gin.H{"message": "Authentication failed"})
+		return
+	}
+
+	respondWithTokens(c, user)
+}
+
+// RefreshTokens exchanges a refresh token for a new access token and a new refresh token
+func RefreshTokens(c *gin.Context) {
+	var req RefreshReq
+	if err := c.BindJSON(&req); err != nil {
+		c.IndentedJSON(http.StatusBadRequest, gin.H{"message": "JSON is invalid"})
+		return
+	}
+
+	token, err := UseRefreshToken(hashToken(req.RefreshToken))
+	if err != nil {
+		c.IndentedJSON(http.StatusInternalServerError, gin.H{"message": err.Error()})
+		return
+	}
+	if token.Id.IsZero() {
+		c.IndentedJSON(http.StatusUnauthorized, gin.H{"message": "The refresh token is invalid or has expired"})
+		return
+	}
+
+	user := FindUser(bson.M{"_id": token.UserId})
+	if user.Id.IsZero() {
+		c.IndentedJSON(http.StatusUnauthorized, gin.H{"message": "User not found"})
+		return
+	}
+
+	respondWithTokens(c, user)
+}
+
+// Logout revokes a refresh token, the access token remaining valid until it expires
+func Logout(c *gin.Context) {
+	var req RefreshReq
+	if err := c.BindJSON(&req); err != nil {
+		c.IndentedJSON(http.StatusBadRequest, gin.H{"message": "JSON is invalid"})
+		return
+	}
+
+	if _, err := UseRefreshToken(hashToken(req.RefreshToken)); err != nil {
+		c.IndentedJSON(http.StatusInternalServerError, gin.H{"message": err.Error()})
+		return
+	}
+
+	c.IndentedJSON(http.StatusNoContent, nil)
+}
+
+// UpdateUserRole changes the role of a user, which applies at once to the requests made with his access tokens, and
+// revokes his refresh tokens
+func UpdateUserRole(c *gin.Context) {
+	var req RoleReq
+	if err := c.BindJSON(&req); err != nil {
+		c.IndentedJSON(http.StatusBadRequest, gin.H{"message": "JSON is invalid"})
+		return
+	}
+
+	if _, ok := roleScopes[req.Role]; !ok {
+		c.IndentedJSON(http.StatusBadRequest, gin.H{"message": "The role must be viewer, editor or admin"})
+		return
+	}
+
+	id, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		c.IndentedJSON(http.StatusBadRequest, gin.H{"message": "Id is invalid"})
+		return
+	}
+
+	user := FindUser(bson.M{"_id": id})
+	if user.Id.IsZero() {
+		c.IndentedJSON(http.StatusNotFound, gin.H{"message": "User not found"})
+		return
+	}
+
+	if _, err = UpdateUserById(user.Id.Hex(), bson.M{"role": req.Role}); err != nil {
+		c.IndentedJSON(http.StatusInternalServerError, gin.H{"message": err.Error()})
+		return
+	}
+	if _, err = RevokeRefreshTokensOfUser(user.Id); err != nil {
+		c.IndentedJSON(http.StatusInternalServerError, gin.H{"message": err.Error()})
+		return
+	}
+
+	user.Role = req.Role
+	c.IndentedJSON(http.StatusOK, user)
+}
+
+// respondWithTokens answers with a new access token and a new refresh token for the user
+func respondWithTokens(c *gin.Context, user User) {
+	accessToken, err := newAccessToken(user)
+	if err != nil {
+		c.IndentedJSON(http.StatusInternalServerError, gin.H{"message": err.Error()})
+		return
+	}
+
+	random := make([]byte, 32)
+	if _, err = rand.Read(random); err != nil {
+		c.IndentedJSON(http.StatusInternalServerError, gin.H{"message": err.Error()})
+		return
+	}
+	refreshToken := hex.EncodeToString(random)
+
+	now := time.Now()
+	_, err = AddRefreshToken(RefreshToken{
+		UserId:    user.Id,
+		Hash:      hashToken(refreshToken),
+		CreatedAt: now,
+		ExpiresAt: now.Add(refreshTokenDuration),
+	})
+	if err != nil {
+		c.IndentedJSON(http.StatusInternalServerError, gin.H{"message": err.Error()})
+		return
+	}
+
+	c.IndentedJSON(http.StatusOK, gin.H{
+		"access_token":  accessToken,
+		"token_type":    "Bearer",
+		"expires_in":    int(accessTokenDuration.Seconds()),
+		"refresh_token": refreshToken,
+		"user":          user,
+	})
+}
+
+// hashToken returns the hexadecimal SHA-256 hash of a token, which is how the tokens are stored
+func hashToken(token string) string {
+	hash := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(hash[:])
+}
@@ -0,0 +1,61 @@
+package film_api
+
+import (
+	"context"
+	"filmflix/db_connection"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"time"
+)
+
+var refreshTokenColl *mongo.Collection
+
+func InitRefreshTokenCollection(client *mongo.Client) {
+	refreshTokenColl = db_connection.GetCollection(client, "films", "refresh_tokens")
+}
+
+func AddRefreshToken(token RefreshToken) (RefreshToken, error) {
+	token.Id = primitive.NewObjectID()
+
+	_, err := refreshTokenColl.InsertOne(context.TODO(), token)
+	if err != nil {
+		return RefreshToken{}, err
+	}
+
+	return token, nil
+}
+
+// UseRefreshToken revokes the active refresh token with the given hash and returns it, so that each refresh token can
+// only be used once. The returned token is empty if there is no such active token.
+func UseRefreshToken(hash string) (RefreshToken, error) {
+	var token RefreshToken
+	now := time.Now()
+
+	err := refreshTokenColl.FindOneAndUpdate(context.TODO(),
+		bson.M{"hash": hash, "revoked_at": bson.M{"$exists": false}, "expires_at": bson.M{"$gt": now}},
+		bson.M{"$set": bson.M{"revoked_at": now}},
+	).Decode(&token)
+
+	if err == mongo.ErrNoDocuments {
+		return token, nil
+	}
+	if err != nil {
+		return RefreshToken{}, err
+	}
+
+	return token, nil
+}
+
+// RevokeRefreshTokensOfUser revokes all the active refresh tokens of a user and returns the number of revoked tokens
+func RevokeRefreshTokensOfUser(userId primitive.ObjectID) (int64, error) {
+	result, err := refreshTokenColl.UpdateMany(context.TODO(),
+		bson.M{"user": userId, "revoked_at": bson.M{"$exists": false}},
+		bson.M{"$set": bson.M{"revoked_at": time.Now()}},
+	)
+	if err != nil {
+		return 0, err
+	}
+
+	return result.ModifiedCount, nil
+}
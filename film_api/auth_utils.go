@@ -1,11 +1,213 @@
 package film_api
 
 import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/hex"
+	"fmt"
 	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson"
+	"net/http"
 	"os"
+	"strings"
+	"time"
 )
 
-func CheckAuthKey(c *gin.Context) bool {
-	authKey, exists := c.GetQuery("auth")
-	return exists && authKey == os.Getenv("ADMIN_KEY")
+// Scopes of the API keys: admin grants everything, and the write scopes grant read
+const (
+	readScope        = "read"
+	writeFilmsScope  = "write:films"
+	writePeopleScope = "write:people"
+	adminScope       = "admin"
+)
+
+var apiKeyScopes = []string{readScope, writeFilmsScope, writePeopleScope, adminScope}
+
+// Roles of the users, each one granting the scopes of roleScopes
+const (
+	viewerRole = "viewer"
+	editorRole = "editor"
+	adminRole  = "admin"
+)
+
+var roleScopes = map[string][]string{
+	viewerRole: {readScope},
+	editorRole: {readScope, writeFilmsScope, writePeopleScope},
+	adminRole:  {readScope, adminScope},
+}
+
+// apiKeyPrefix starts all the API keys, so that they are easy to recognize
+const apiKeyPrefix = "ffx"
+
+// RequireScope is a middleware only letting through the requests authenticated with "Authorization: Bearer <token>"
+// granting the scope, the token being either the access token of a user or an API key
+func RequireScope(scope string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if checkScope(c, scope) {
+			c.Next()
+		}
+	}
+}
+
+// RequireReadScope is a middleware letting through the read requests without a token, since the catalogue is public,
+// but requiring the read scope from the token of the ones which have one, so that a key may be limited to reading
+func RequireReadScope(c *gin.Context) {
+	if (c.Request.Method != http.MethodGet && c.Request.Method != http.MethodHead) || len(bearerToken(c)) == 0 {
+		c.Next()
+		return
+	}
+
+	if checkScope(c, readScope) {
+		c.Next()
+	}
+}
+
+// checkScope tells whether the token of the request grants the scope. If not, it answers with an error and aborts the
+// request.
+func checkScope(c *gin.Context, scope string) bool {
+	scopes := authenticate(c)
+	if scopes == nil {
+		c.Header("WWW-Authenticate", `Bearer realm="filmflix"`)
+		c.IndentedJSON(http.StatusUnauthorized, gin.H{"message": "Authentication failed"})
+		c.Abort()
+		return false
+	}
+	if !grantsScope(scopes, scope) {
+		c.IndentedJSON(http.StatusForbidden, gin.H{"message": "Permission denied"})
+		c.Abort()
+		return false
+	}
+
+	return true
+}
+
+// authenticate returns the scopes granted by the token of the request, or nil if it is missing or invalid. They are
+// kept in the context, so that a request checked by several middlewares is authenticated once.
+func authenticate(c *gin.Context) []string {
+	if scopes, exists := c.Get("scopes"); exists {
+		return scopes.([]string)
+	}
+
+	token := bearerToken(c)
+
+	var scopes []string
+	if isJWT(token) {
+		// The scopes are the ones of the stored role of the user rather than the one of the token, so that a user
+		// who is demoted or deleted loses their rights at once
+		if user, claims, ok := tokenUser(token); ok {
+			claims.Role = user.role()
+			scopes = roleScopes[claims.Role]
+			c.Set("claims", claims)
+			c.Set("user", user)
+		}
+	} else if apiKey, ok := checkApiKey(token); ok {
+		scopes = apiKey.Scopes
+		c.Set("api_key", apiKey)
+	}
+
+	c.Set("scopes", scopes)
+	return scopes
+}
+
+// checkApiKey returns the active API key matching the token and records its use. The ADMIN_KEY environment variable
+// is a key with all the scopes, used to create the first keys and the first admins.
+func checkApiKey(token string) (ApiKey, bool) {
+	apiKey, ok := findApiKey(token)
+	if ok && !apiKey.Id.IsZero() {
+		go func() {
+			// The last use date is informative, so failing to record it doesn't fail the request
+			if err := TouchApiKey(apiKey.Id); err != nil {
+				fmt.Printf("Recording the use of the API key %v failed: %v\n", apiKey.Id.Hex(), err)
+			}
+		}()
+	}
+
+	return apiKey, ok
+}
+
+// findApiKey returns the active API key matching the token, comparing the secrets in constant time
+func findApiKey(token string) (ApiKey, bool) {
+	if len(token) == 0 {
+		return ApiKey{}, false
+	}
+
+	if adminKey := os.Getenv("ADMIN_KEY"); len(adminKey) > 0 && subtle.ConstantTimeCompare([]byte(token), []byte(adminKey)) == 1 {
+		return ApiKey{Name: "ADMIN_KEY", Scopes: []string{adminScope}}, true
+	}
+
+	// The keys are "ffx_<prefix>_<secret>", the prefix being stored in clear to find the key
+	parts := strings.Split(token, "_")
+	if len(parts) != 3 || parts[0] != apiKeyPrefix {
+		return ApiKey{}, false
+	}
+
+	apiKey := FindApiKey(bson.M{"prefix": parts[1]})
+	if apiKey.Id.IsZero() || !apiKey.isActive() {
+		return ApiKey{}, false
+	}
+
+	if subtle.ConstantTimeCompare([]byte(hashToken(token)), []byte(apiKey.Hash)) != 1 {
+		return ApiKey{}, false
+	}
+
+	return apiKey, true
+}
+
+// RateLimitKey identifies the client of a request for the rate limiting, by its user or its API key. The requests
+// without a valid token are only identified by their IP, so that a client can't get new quotas with made-up tokens.
+func RateLimitKey(c *gin.Context) string {
+	token := bearerToken(c)
+	if len(token) == 0 {
+		return ""
+	}
+
+	if isJWT(token) {
+		if claims, err := parseAccessToken(token); err == nil {
+			return "user:" + claims.Subject
+		}
+	} else if apiKey, ok := findApiKey(token); ok {
+		if apiKey.Id.IsZero() {
+			return "key:" + apiKey.Name
+		}
+		return "key:" + apiKey.Id.Hex()
+	}
+
+	return ""
+}
+
+// bearerToken returns the token of the "Authorization: Bearer <token>" header, or an empty string if there is none
+func bearerToken(c *gin.Context) string {
+	header := c.GetHeader("Authorization")
+	if len(header) < 7 || !strings.EqualFold(header[:7], "Bearer ") {
+		return ""
+	}
+	return strings.TrimSpace(header[7:])
+}
+
+// isActive tells whether the key is neither revoked nor expired
+func (key ApiKey) isActive() bool {
+	return key.RevokedAt == nil && (key.ExpiresAt == nil || key.ExpiresAt.After(time.Now()))
+}
+
+// grantsScope tells whether the scope is one of the scopes or is implied by one of them
+func grantsScope(scopes []string, scope string) bool {
+	for _, s := range scopes {
+		if s == adminScope || s == scope || (scope == readScope && strings.HasPrefix(s, "write:")) {
+			return true
+		}
+	}
+	return false
+}
+
+// generateApiKey returns a new API key, its prefix and its hash
+func generateApiKey() (string, string, string, error) {
+	random := make([]byte, 4+32)
+	if _, err := rand.Read(random); err != nil {
+		return "", "", "", err
+	}
+
+	prefix := hex.EncodeToString(random[:4])
+	key := apiKeyPrefix + "_" + prefix + "_" + hex.EncodeToString(random[4:])
+
+	return key, prefix, hashToken(key), nil
 }
@@ -0,0 +1,54 @@
+package film_api
+
+import (
+	"github.com/gin-gonic/gin"
+	"net/http"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+const (
+	autocompleteMinLength    = 2
+	autocompleteDefaultLimit = 10
+	autocompleteMaxLimit     = 50
+)
+
+func InitAutocompleteApiRoutes(apiRoutes *gin.RouterGroup) {
+	apiRoutes.GET("/autocomplete", GetAutocomplete)
+}
+
+// GetAutocomplete returns the films, actors and directors whose title or name starts with the "q" query param
+func GetAutocomplete(c *gin.Context) {
+	query := strings.TrimSpace(c.Query("q"))
+	if utf8.RuneCountInString(query) < autocompleteMinLength {
+		c.IndentedJSON(http.StatusOK, []Suggestion{})
+		return
+	}
+
+	types := parseItemTypes(c)
+
+	limit := autocompleteDefaultLimit
+	if l := c.Query("limit"); len(l) > 0 {
+		limit, _ = strconv.Atoi(l)
+	}
+	if limit <= 0 || limit > autocompleteMaxLimit {
+		limit = autocompleteMaxLimit
+	}
+
+	c.IndentedJSON(http.StatusOK, catalogueAutocompleteIndex.suggest(query, types, limit))
+}
+
+// parseItemTypes returns the set of item types given in the "types" query param, all types if it is missing
+func parseItemTypes(c *gin.Context) map[string]bool {
+	t := c.Query("types")
+	if len(t) == 0 {
+		return map[string]bool{"film": true, "actor": true, "director": true}
+	}
+
+	types := make(map[string]bool)
+	for _, itemType := range strings.Split(t, ",") {
+		types[strings.TrimSpace(itemType)] = true
+	}
+	return types
+}
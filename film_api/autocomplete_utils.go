@@ -0,0 +1,397 @@
+package film_api
+
+import (
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"sort"
+	"strings"
+	"sync"
+	"unicode"
+)
+
+// Suggestion is an item proposed to complete the text typed by a user
+type Suggestion struct {
+	Type  string `json:"type"` // Type is either "film", "actor" or "director"
+	Id    string `json:"id"`
+	Slug  string `json:"slug,omitempty"`
+	Label string `json:"label"` // Label is the title or the name of the item
+	Match string `json:"match"` // Match is the indexed text which starts with the typed text
+}
+
+// trieEntry is a suggestion indexed in the trie, wordStart telling whether the key starts at a word in the middle of the text
+type trieEntry struct {
+	suggestion *Suggestion
+	wordStart  bool
+}
+
+// trieTopSize is the number of suggestions of each type kept by the nodes of the trie, the most suggestions a request can get
+const trieTopSize = autocompleteMaxLimit
+
+type trieNode struct {
+	children map[rune]*trieNode
+	entries  []trieEntry // entries are the suggestions whose key ends at this node
+	// top holds, for each type, the best entries of the subtree of the node in the order of the suggestions, with a
+	// single entry per item, so that a prefix is completed without walking its subtree
+	top map[string][]trieEntry
+}
+
+func newTrieNode() *trieNode {
+	return &trieNode{children: make(map[rune]*trieNode), top: make(map[string][]trieEntry)}
+}
+
+// isBetter tells whether an entry comes before another one in the suggestions: the items whose title or name starts
+// with the text come first, then the ones with a word starting with it, the shortest matches first
+func (entry trieEntry) isBetter(other trieEntry) bool {
+	if entry.wordStart != other.wordStart {
+		return !entry.wordStart
+	}
+	if len(entry.suggestion.Match) != len(other.suggestion.Match) {
+		return len(entry.suggestion.Match) < len(other.suggestion.Match)
+	}
+	return entry.suggestion.Match < other.suggestion.Match
+}
+
+func (node *trieNode) insert(key string, entry trieEntry) {
+	node.offer(entry)
+	for _, char := range key {
+		child, found := node.children[char]
+		if !found {
+			child = newTrieNode()
+			node.children[char] = child
+		}
+		node = child
+		node.offer(entry)
+	}
+	node.entries = append(node.entries, entry)
+}
+
+// offer adds the entry to the best entries of the node if it is one of them, replacing the entry of the same item
+func (node *trieNode) offer(entry trieEntry) {
+	top := node.top[entry.suggestion.Type]
+	for i, other := range top {
+		if other.suggestion.Id == entry.suggestion.Id {
+			if !entry.isBetter(other) {
+				return
+			}
+			top = append(top[:i], top[i+1:]...)
+			break
+		}
+	}
+
+	i := sort.Search(len(top), func(i int) bool { return entry.isBetter(top[i]) })
+	if i >= trieTopSize {
+		return
+	}
+	top = append(top, trieEntry{})
+	copy(top[i+1:], top[i:])
+	top[i] = entry
+	if len(top) > trieTopSize {
+		top = top[:trieTopSize]
+	}
+	node.top[entry.suggestion.Type] = top
+}
+
+// remove removes the entries of the suggestion whose key is the given one, and computes again the best entries of
+// the nodes of the key, the nodes left empty being removed
+func (node *trieNode) remove(key string, suggestion *Suggestion) {
+	path := []*trieNode{node}
+	chars := []rune(key)
+	for _, char := range chars {
+		node = node.children[char]
+		if node == nil {
+			return
+		}
+		path = append(path, node)
+	}
+
+	entries := node.entries[:0]
+	for _, entry := range node.entries {
+		if entry.suggestion != suggestion {
+			entries = append(entries, entry)
+		}
+	}
+	node.entries = entries
+
+	for i := len(path) - 1; i >= 0; i-- {
+		node = path[i]
+		if i > 0 && len(node.entries) == 0 && len(node.children) == 0 {
+			delete(path[i-1].children, chars[i-1])
+			continue
+		}
+
+		node.top = make(map[string][]trieEntry)
+		for _, entry := range node.entries {
+			node.offer(entry)
+		}
+		for _, child := range node.children {
+			for _, top := range child.top {
+				for _, entry := range top {
+					node.offer(entry)
+				}
+			}
+		}
+	}
+}
+
+// find returns the node matching exactly the given prefix, or nil if no key starts with it
+func (node *trieNode) find(prefix string) *trieNode {
+	for _, char := range prefix {
+		node = node.children[char]
+		if node == nil {
+			return nil
+		}
+	}
+	return node
+}
+
+// autocompleteIndex holds the prefix tree of the titles and names, built in memory and updated lazily after each write
+// with the written items whose texts changed
+type autocompleteIndex struct {
+	updateMutex sync.Mutex                 // updateMutex lets a single request update the index at once
+	mutex       sync.RWMutex               // mutex guards the fields below, the tree being updated in place
+	dirty       bool                       // dirty tells whether the whole catalogue must be read again
+	changed     map[string]map[string]bool // changed holds the ids of the items written since the last update, by type
+	root        *trieNode
+	items       map[string][]*Suggestion // items are the indexed texts of each item, by type and id
+	texts       []*Suggestion            // texts are all the indexed titles and names, used by the search
+}
+
+var catalogueAutocompleteIndex = &autocompleteIndex{dirty: true, changed: make(map[string]map[string]bool), root: newTrieNode(), items: make(map[string][]*Suggestion)}
+
+func (index *autocompleteIndex) invalidate() {
+	index.mutex.Lock()
+	index.dirty = true
+	index.mutex.Unlock()
+}
+
+// itemsChanged marks the items of the given type, "film" or "person", to be read again by the next update
+func (index *autocompleteIndex) itemsChanged(itemType string, ids []string) {
+	if itemType != "film" && itemType != "person" {
+		return
+	}
+
+	index.mutex.Lock()
+	defer index.mutex.Unlock()
+	if index.changed[itemType] == nil {
+		index.changed[itemType] = make(map[string]bool)
+	}
+	for _, id := range ids {
+		index.changed[itemType][id] = true
+	}
+}
+
+// update brings the tree up to date if the catalogue changed since the last update: the written items are read
+// without blocking the requests using the index, and only the ones whose texts changed are removed and inserted again
+func (index *autocompleteIndex) update() {
+	index.updateMutex.Lock()
+	defer index.updateMutex.Unlock()
+
+	// The items written while the index is updated are read by the next update
+	index.mutex.Lock()
+	dirty, changed := index.dirty, index.changed
+	index.dirty, index.changed = false, make(map[string]map[string]bool)
+	index.mutex.Unlock()
+	if !dirty && len(changed) == 0 {
+		return
+	}
+
+	// The whole catalogue is read by the next request if it can't be read
+	defer func() {
+		if err := recover(); err != nil {
+			index.invalidate()
+			panic(err)
+		}
+	}()
+
+	var items map[string][]*Suggestion
+	var keys []string
+	if dirty {
+		items = catalogueTexts(bson.M{}, bson.M{})
+	} else {
+		var filmFilter, personFilter bson.M
+		if ids := changedIds(changed["film"]); len(ids) > 0 {
+			filmFilter = bson.M{"_id": bson.M{"$in": ids}}
+			for _, id := range ids {
+				keys = append(keys, "film"+id.Hex())
+			}
+		}
+		if ids := changedIds(changed["person"]); len(ids) > 0 {
+			personFilter = bson.M{"_id": bson.M{"$in": ids}}
+			for _, id := range ids {
+				keys = append(keys, "actor"+id.Hex(), "director"+id.Hex())
+			}
+		}
+		items = catalogueTexts(filmFilter, personFilter)
+	}
+
+	index.mutex.Lock()
+	defer index.mutex.Unlock()
+
+	if dirty {
+		for key := range index.items {
+			keys = append(keys, key)
+		}
+	}
+	for _, key := range keys {
+		texts, found := index.items[key]
+		if !found {
+			continue
+		}
+		if current, found := items[key]; !found || !sameTexts(texts, current) {
+			for _, text := range texts {
+				for _, key := range indexKeys(text.Match) {
+					index.root.remove(key.key, text)
+				}
+			}
+			delete(index.items, key)
+		}
+	}
+	for key, texts := range items {
+		if _, found := index.items[key]; !found {
+			for _, text := range texts {
+				for _, key := range indexKeys(text.Match) {
+					index.root.insert(key.key, trieEntry{suggestion: text, wordStart: key.wordStart})
+				}
+			}
+			index.items[key] = texts
+		}
+	}
+
+	texts := make([]*Suggestion, 0, len(index.texts))
+	for _, itemTexts := range index.items {
+		texts = append(texts, itemTexts...)
+	}
+	index.texts = texts
+}
+
+// changedIds returns the valid ids of a set of changed items
+func changedIds(changed map[string]bool) []primitive.ObjectID {
+	var ids []primitive.ObjectID
+	for idString := range changed {
+		if id, err := primitive.ObjectIDFromHex(idString); err == nil {
+			ids = append(ids, id)
+		}
+	}
+	return ids
+}
+
+// load returns the indexed texts, updating the index first if the catalogue changed. The returned slice is never
+// modified afterwards, so it can be read without holding the lock.
+func (index *autocompleteIndex) load() []*Suggestion {
+	index.update()
+
+	index.mutex.RLock()
+	defer index.mutex.RUnlock()
+	return index.texts
+}
+
+// catalogueTexts returns the titles and names of the films and the people matching the filters, by type and id. The
+// films or the people are not read at all when their filter is nil.
+func catalogueTexts(filmFilter bson.M, personFilter bson.M) map[string][]*Suggestion {
+	items := make(map[string][]*Suggestion)
+	addText := func(suggestion Suggestion, text string) {
+		if len(text) == 0 {
+			return
+		}
+		suggestion.Match = text
+		key := suggestion.Type + suggestion.Id
+		items[key] = append(items[key], &suggestion)
+	}
+
+	if filmFilter != nil {
+		for _, film := range FindFilms(filmFilter, 0) {
+			suggestion := Suggestion{Type: "film", Id: film.Id.Hex(), Slug: film.Slug, Label: film.Title}
+			addText(suggestion, film.Title)
+			addText(suggestion, film.OriginalTitle)
+			for _, localization := range film.Localizations {
+				addText(suggestion, localization.Title)
+			}
+		}
+	}
+	if personFilter != nil {
+		for _, actor := range FindActors(personFilter, 0) {
+			addText(Suggestion{Type: "actor", Id: actor.Id.Hex(), Slug: actor.Slug, Label: actor.Name}, actor.Name)
+		}
+		for _, director := range FindDirectors(personFilter, 0) {
+			addText(Suggestion{Type: "director", Id: director.Id.Hex(), Slug: director.Slug, Label: director.Name}, director.Name)
+		}
+	}
+
+	return items
+}
+
+func sameTexts(a []*Suggestion, b []*Suggestion) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if *a[i] != *b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// indexKey is a key of a text in the tree, wordStart telling whether it starts at a word in the middle of the text
+type indexKey struct {
+	key       string
+	wordStart bool
+}
+
+// indexKeys returns the keys of a text in the tree, which are its folded and transliterated forms from the start of
+// each of their words
+func indexKeys(text string) []indexKey {
+	forms := []string{foldText(text)}
+	if transliterated := foldText(transliterate(text)); transliterated != forms[0] {
+		forms = append(forms, transliterated)
+	}
+
+	var keys []indexKey
+	for _, form := range forms {
+		chars := []rune(form)
+		for i := range chars {
+			if i == 0 || (!isWordChar(chars[i-1]) && isWordChar(chars[i])) {
+				keys = append(keys, indexKey{key: string(chars[i:]), wordStart: i > 0})
+			}
+		}
+	}
+	return keys
+}
+
+func isWordChar(char rune) bool {
+	return unicode.IsLetter(char) || unicode.IsNumber(char)
+}
+
+// suggest returns at most limit suggestions of the given types starting with the given text.
+// The items whose title or name starts with the text come first, then the ones with a word starting with it.
+func (index *autocompleteIndex) suggest(text string, types map[string]bool, limit int) []Suggestion {
+	index.update()
+
+	index.mutex.RLock()
+	defer index.mutex.RUnlock()
+
+	node := index.root.find(foldText(strings.TrimSpace(text)))
+	if node == nil {
+		return []Suggestion{}
+	}
+
+	var entries []trieEntry
+	for itemType, top := range node.top {
+		if types[itemType] {
+			entries = append(entries, top...)
+		}
+	}
+	sort.Slice(entries, func(i, j int) bool {
+		return entries[i].isBetter(entries[j])
+	})
+
+	if len(entries) > limit {
+		entries = entries[:limit]
+	}
+	suggestions := make([]Suggestion, len(entries))
+	for i, entry := range entries {
+		suggestions[i] = *entry.suggestion
+	}
+
+	return suggestions
+}
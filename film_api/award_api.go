@@ -0,0 +1,212 @@
+package film_api
+
+import (
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"net/http"
+	"strconv"
+)
+
+// Award is a winning or a nomination of a film, and optionally of one of its actors or directors, at a ceremony
+type Award struct {
+	Id         primitive.ObjectID `json:"id" bson:"_id,omitempty"`
+	Ceremony   string             `json:"ceremony" bson:"ceremony"`
+	Year       int                `json:"year" bson:"year"`
+	Category   string             `json:"category" bson:"category"`
+	Winner     bool               `json:"winner" bson:"winner"` // Winner is false for a nomination
+	FilmId     string             `json:"film" bson:"film"`
+	PersonId   string             `json:"person,omitempty" bson:"person,omitempty"`
+	PersonKind string             `json:"person_kind,omitempty" bson:"person_kind,omitempty"` // PersonKind is either "actor" or "director"
+}
+
+func InitAwardApiRoutes(apiRoutes *gin.RouterGroup, client *mongo.Client) {
+	InitAwardCollection(client)
+	InitAuditCollection(client)
+
+	awardRoutes := apiRoutes.Group("/awards")
+	awardRoutes.Use(Audit("award", awardColl))
+	awardRoutes.GET("/", GetAwards)
+	awardRoutes.GET("/ceremonies", GetCeremonies)
+	awardRoutes.GET("/ceremonies/:ceremony", GetCeremonyAwards)
+	awardRoutes.GET("/:id", GetAwardById)
+	awardRoutes.POST("/", RequireScope(writeFilmsScope), PostAward)
+	awardRoutes.PATCH("/:id", RequireScope(writeFilmsScope), UpdateAward)
+	awardRoutes.DELETE("/:id", RequireScope(writeFilmsScope), DeleteAward)
+}
+
+// GetAwards returns the awards, optionally filtered by the ceremony, year, film, person and winner query params
+func GetAwards(c *gin.Context) {
+	limit := 0
+	if l := c.Query("limit"); len(l) > 0 {
+		limit, _ = strconv.Atoi(l)
+	}
+
+	filter := bson.M{}
+	for _, field := range []string{"ceremony", "category", "film", "person"} {
+		if value := c.Query(field); len(value) > 0 {
+			filter[field] = value
+		}
+	}
+	if y := c.Query("year"); len(y) > 0 {
+		year, err := strconv.Atoi(y)
+		if err != nil {
+			c.IndentedJSON(http.StatusBadRequest, gin.H{"message": "year must be a number"})
+			return
+		}
+		filter["year"] = year
+	}
+	if w := c.Query("winner"); len(w) > 0 {
+		winner, err := strconv.ParseBool(w)
+		if err != nil {
+			c.IndentedJSON(http.StatusBadRequest, gin.H{"message": "winner must be a boolean"})
+			return
+		}
+		filter["winner"] = winner
+	}
+
+	c.IndentedJSON(http.StatusOK, FindAwards(filter, limit))
+}
+
+func GetCeremonies(c *gin.Context) {
+	c.IndentedJSON(http.StatusOK, FindCeremonies())
+}
+
+func GetCeremonyAwards(c *gin.Context) {
+	filter := bson.M{"ceremony": c.Param("ceremony")}
+	if y := c.Query("year"); len(y) > 0 {
+		year, err := strconv.Atoi(y)
+		if err != nil {
+			c.IndentedJSON(http.StatusBadRequest, gin.H{"message": "year must be a number"})
+			return
+		}
+		filter["year"] = year
+	}
+
+	c.IndentedJSON(http.StatusOK, FindAwards(filter, 0))
+}
+
+func GetAwardById(c *gin.Context) {
+	id, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		c.IndentedJSON(http.StatusBadRequest, gin.H{"message": "Id is invalid"})
+		return
+	}
+
+	award := FindAward(bson.M{"_id": id})
+	if award.Id.IsZero() {
+		c.IndentedJSON(http.StatusNotFound, gin.H{"message": "Award not found"})
+		return
+	}
+
+	c.IndentedJSON(http.StatusOK, award)
+}
+
+// GetFilmAwards returns the awards of the film of the url
+func GetFilmAwards(c *gin.Context) {
+	c.IndentedJSON(http.StatusOK, FindAwards(bson.M{"film": c.Param("id")}, 0))
+}
+
+// GetActorAwards returns the awards of the actor of the url
+func GetActorAwards(c *gin.Context) {
+	c.IndentedJSON(http.StatusOK, FindAwards(bson.M{"person": c.Param("id"), "person_kind": "actor"}, 0))
+}
+
+// GetDirectorAwards returns the awards of the director of the url
+func GetDirectorAwards(c *gin.Context) {
+	c.IndentedJSON(http.StatusOK, FindAwards(bson.M{"person": c.Param("id"), "person_kind": "director"}, 0))
+}
+
+func PostAward(c *gin.Context) {
+	var newAward Award
+	if err := c.BindJSON(&newAward); err != nil {
+		return
+	}
+
+	if valid, msg := isAwardValid(newAward); !valid {
+		c.IndentedJSON(http.StatusBadRequest, msg)
+		return
+	}
+
+	newAward, err := AddAward(newAward)
+	if err != nil {
+		c.IndentedJSON(http.StatusBadRequest, gin.H{"message": err.Error()})
+		return
+	}
+
+	c.IndentedJSON(http.StatusCreated, newAward)
+}
+
+// UpdateAward updates the fields of an award given in the body, the other ones being kept
+func UpdateAward(c *gin.Context) {
+	id, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		c.IndentedJSON(http.StatusBadRequest, gin.H{"message": "Id is invalid"})
+		return
+	}
+
+	award := FindAward(bson.M{"_id": id})
+	if award.Id.IsZero() {
+		c.IndentedJSON(http.StatusNotFound, gin.H{"message": "Award not found"})
+		return
+	}
+
+	// The body is decoded over the current award, so that only its fields are changed
+	if err := c.BindJSON(&award); err != nil {
+		c.IndentedJSON(http.StatusBadRequest, gin.H{"message": err.Error()})
+		return
+	}
+
+	if valid, msg := isAwardValid(award); !valid {
+		c.IndentedJSON(http.StatusBadRequest, msg)
+		return
+	}
+
+	result, err := ReplaceAward(id.Hex(), award)
+	if err != nil {
+		c.IndentedJSON(http.StatusBadRequest, gin.H{"message": err.Error()})
+		return
+	}
+
+	c.IndentedJSON(http.StatusNoContent, result)
+}
+
+func DeleteAward(c *gin.Context) {
+	if !primitive.IsValidObjectID(c.Param("id")) {
+		c.IndentedJSON(http.StatusBadRequest, gin.H{"message": "Id is invalid"})
+		return
+	}
+
+	result := DeleteItemById(awardColl, c.Param("id"))
+
+	if result == 0 {
+		c.IndentedJSON(http.StatusNotModified, gin.H{"message": "No award with the specified id"})
+		return
+	}
+
+	c.IndentedJSON(http.StatusNoContent, result)
+}
+
+// isAwardValid checks that the required fields of the award are filled and that its film and person exist
+func isAwardValid(award Award) (bool, gin.H) {
+	if len(award.Ceremony) == 0 || len(award.Category) == 0 || award.Year <= 0 {
+		return false, gin.H{"message": "The ceremony, the year and the category are required"}
+	}
+
+	if valid, msg := AreFilmsIdsValid([]string{award.FilmId}); !valid {
+		return false, msg
+	}
+
+	if len(award.PersonId) == 0 && len(award.PersonKind) == 0 {
+		return true, nil
+	}
+	switch award.PersonKind {
+	case "actor":
+		return AreActorsIdsValid([]Role{{ActorId: award.PersonId}})
+	case "director":
+		return AreDirectorsIdsValid([]string{award.PersonId})
+	}
+
+	return false, gin.H{"message": "The kind of the person must be either actor or director"}
+}
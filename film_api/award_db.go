@@ -0,0 +1,142 @@
+package film_api
+
+import (
+	"context"
+	"filmflix/db_connection"
+	"fmt"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+var awardColl *mongo.Collection
+
+func InitAwardCollection(client *mongo.Client) {
+	awardColl = db_connection.GetCollection(client, "films", "awards")
+}
+
+// FindAwards retrieves the awards matching the filter, except the ones of the films in the trash, the most recent
+// ceremonies first
+func FindAwards(filter bson.M, maxCount int) []Award {
+	results := []Award{}
+	limit := int64(maxCount)
+	cursor, err := awardColl.Find(context.TODO(), withoutTrashedFilms(filter), &options.FindOptions{Limit: &limit, Sort: bson.D{{Key: "year", Value: -1}, {Key: "ceremony", Value: 1}, {Key: "category", Value: 1}}})
+	if err != nil {
+		panic(err)
+	}
+
+	if err = cursor.All(context.TODO(), &results); err != nil {
+		panic(err)
+	}
+
+	return results
+}
+
+func FindAward(filter bson.M) Award {
+	var award Award
+	err := awardColl.FindOne(context.TODO(), filter).Decode(&award)
+
+	if err == mongo.ErrNoDocuments {
+		fmt.Printf("No document was found\n")
+		return award
+	}
+	if err != nil {
+		panic(err)
+	}
+
+	return award
+}
+
+func AddAward(award Award) (Award, error) {
+	award.Id = primitive.NewObjectID()
+
+	_, err := awardColl.InsertOne(context.TODO(), award)
+	if err != nil {
+		return Award{}, err
+	}
+
+	return award, nil
+}
+
+func ReplaceAward(idString string, newAward Award) (int64, error) {
+	id, err := primitive.ObjectIDFromHex(idString)
+	if err != nil {
+		return 0, err
+	}
+	newAward.Id = id
+
+	result, err := awardColl.ReplaceOne(context.TODO(), bson.M{"_id": id}, newAward)
+	if err != nil {
+		return 0, err
+	}
+
+	return result.ModifiedCount, nil
+}
+
+// FindAwardedFilmsIds returns the ids of the films which won an award, or which were at least nominated if onlyWinners is false
+func FindAwardedFilmsIds(onlyWinners bool) []primitive.ObjectID {
+	filter := bson.M{}
+	if onlyWinners {
+		filter["winner"] = true
+	}
+
+	ids, err := awardColl.Distinct(context.TODO(), "film", filter)
+	if err != nil {
+		panic(err)
+	}
+
+	filmsIds := make([]primitive.ObjectID, 0, len(ids))
+	for _, id := range ids {
+		if filmId, err := primitive.ObjectIDFromHex(fmt.Sprint(id)); err == nil {
+			filmsIds = append(filmsIds, filmId)
+		}
+	}
+
+	return filmsIds
+}
+
+// FindCeremonies returns the names of all the ceremonies
+func FindCeremonies() []string {
+	names, err := awardColl.Distinct(context.TODO(), "ceremony", bson.M{})
+	if err != nil {
+		panic(err)
+	}
+
+	ceremonies := make([]string, len(names))
+	for i, name := range names {
+		ceremonies[i] = fmt.Sprint(name)
+	}
+
+	return ceremonies
+}
+
+// RemoveAwardsOfFilm deletes the awards of a film and returns the number of deleted awards
+func RemoveAwardsOfFilm(filmId string) (int64, error) {
+	result, err := awardColl.DeleteMany(context.TODO(), bson.M{"film": filmId})
+	if err != nil {
+		return 0, err
+	}
+
+	return result.DeletedCount, nil
+}
+
+// RemovePersonFromAwards unlinks the awards of a person, which are kept as awards of the film
+func RemovePersonFromAwards(kind string, personId string) (int64, error) {
+	result, err := awardColl.UpdateMany(context.TODO(), bson.M{"person": personId, "person_kind": kind}, bson.M{"$unset": bson.M{"person": "", "person_kind": ""}})
+	if err != nil {
+		return 0, err
+	}
+
+	return result.ModifiedCount, nil
+}
+
+// ReplacePersonInAwards gives the awards of a person to another one of the same kind
+func ReplacePersonInAwards(kind string, oldPersonId string, newPersonId string) (int64, error) {
+	result, err := awardColl.UpdateMany(context.TODO(), bson.M{"person": oldPersonId, "person_kind": kind}, bson.M{"$set": bson.M{"person": newPersonId}})
+	if err != nil {
+		return 0, err
+	}
+
+	return result.ModifiedCount, nil
+}
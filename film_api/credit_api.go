@@ -0,0 +1,266 @@
+package film_api
+
+import (
+	"fmt"
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"net/http"
+	"sort"
+)
+
+// Credit is the participation of a person in a film, as an actor or as a crew member
+type Credit struct {
+	PersonId   string `json:"person" bson:"person"`
+	Name       string `json:"name,omitempty" bson:"-"` // Name is the name of the person, only filled in the responses
+	Department string `json:"department" bson:"department"`
+	Job        string `json:"job" bson:"job"`                                 // Job is the precise job in the department, like "Screenplay" or "Original Music Composer"
+	Character  string `json:"character,omitempty" bson:"character,omitempty"` // Character is the role played, for the acting credits
+	Order      int    `json:"order" bson:"order"`                             // Order is the billing order of the credit in its department
+}
+
+const (
+	actingDepartment    = "acting"
+	directingDepartment = "directing"
+	actorJob            = "Actor"
+	directorJob         = "Director"
+)
+
+// creditDepartments lists the departments of the credits, in the order they are listed in
+var creditDepartments = []string{actingDepartment, directingDepartment, "writing", "production", "sound", "animation", "art", "camera", "editing", "visual_effects", "crew"}
+
+func isCreditDepartment(department string) bool {
+	return departmentRank(department) >= 0
+}
+
+func departmentRank(department string) int {
+	for i, d := range creditDepartments {
+		if d == department {
+			return i
+		}
+	}
+	return -1
+}
+
+// isLegacyCredit tells whether a credit is mirrored in the roles or the directors of the film
+func isLegacyCredit(credit Credit) bool {
+	return credit.Department == actingDepartment || (credit.Department == directingDepartment && credit.Job == directorJob)
+}
+
+// filmCredits returns the credits of a film, sorted by department and billing order. The roles and the directors of
+// the film are the reference for the actors and directors credits, so that the films which were never given credits,
+// or whose roles were updated with PATCH /api/films/<id>/roles, are still fully credited.
+func filmCredits(film Film) []Credit {
+	actors := make(map[string]bool, len(film.Roles))
+	for _, role := range film.Roles {
+		actors[role.ActorId] = true
+	}
+
+	credits := []Credit{}
+	credited := make(map[string]bool)
+	for _, credit := range film.Credits {
+		if credit.Department == actingDepartment && !actors[credit.PersonId] {
+			continue
+		}
+		if credit.Department == directingDepartment && credit.Job == directorJob && !containsString(film.Directors, credit.PersonId) {
+			continue
+		}
+		credits = append(credits, credit)
+		if isLegacyCredit(credit) {
+			credited[credit.Department+credit.PersonId] = true
+		}
+	}
+
+	for i, role := range film.Roles {
+		if !credited[actingDepartment+role.ActorId] {
+			credits = append(credits, Credit{PersonId: role.ActorId, Department: actingDepartment, Job: actorJob, Character: role.Name, Order: len(film.Credits) + i})
+		}
+	}
+	for i, director := range film.Directors {
+		if !credited[directingDepartment+director] {
+			credits = append(credits, Credit{PersonId: director, Department: directingDepartment, Job: directorJob, Order: len(film.Credits) + i})
+		}
+	}
+
+	sortCredits(credits)
+
+	return credits
+}
+
+func sortCredits(credits []Credit) {
+	sort.SliceStable(credits, func(i, j int) bool {
+		if credits[i].Department != credits[j].Department {
+			return departmentRank(credits[i].Department) < departmentRank(credits[j].Department)
+		}
+		return credits[i].Order < credits[j].Order
+	})
+}
+
+// withCredits fills the credits of the films from their roles and directors
+func withCredits(films []Film) {
+	for i := range films {
+		films[i].Credits = filmCredits(films[i])
+	}
+}
+
+// AreCreditsValid checks the departments and the people of the credits, and gives a job to the actors and directors
+// credits which have none
+func AreCreditsValid(credits []Credit) (bool, gin.H) {
+	for i := range credits {
+		credit := &credits[i]
+		if !isCreditDepartment(credit.Department) {
+			return false, gin.H{"message": fmt.Sprintf("Department of credit no. %v is invalid", i)}
+		}
+		if len(credit.Job) == 0 {
+			switch credit.Department {
+			case actingDepartment:
+				credit.Job = actorJob
+			case directingDepartment:
+				credit.Job = directorJob
+			default:
+				return false, gin.H{"message": fmt.Sprintf("Job of credit no. %v is required", i)}
+			}
+		}
+
+		id, err := primitive.ObjectIDFromHex(credit.PersonId)
+		if err != nil {
+			return false, gin.H{"message": fmt.Sprintf("Id of person no. %v is invalid", i)}
+		}
+
+		if FindPerson(bson.M{"_id": id}).Id.IsZero() {
+			return false, gin.H{"message": fmt.Sprintf("Person of credit no. %v does not exist", i)}
+		}
+	}
+
+	return true, nil
+}
+
+func GetFilmCredits(c *gin.Context) {
+	id, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		c.IndentedJSON(http.StatusBadRequest, gin.H{"message": "Id is invalid"})
+		return
+	}
+
+	film := FindFilm(bson.M{"_id": id})
+	if len(film.Title) == 0 {
+		c.IndentedJSON(http.StatusNotFound, gin.H{"message": "Film not found"})
+		return
+	}
+
+	credits := filmCredits(film)
+	names := make(map[string]string)
+	for i, credit := range credits {
+		if _, found := names[credit.PersonId]; !found {
+			personId, _ := primitive.ObjectIDFromHex(credit.PersonId)
+			names[credit.PersonId] = FindPersonById(personId).Name
+		}
+		credits[i].Name = names[credit.PersonId]
+	}
+
+	c.IndentedJSON(http.StatusOK, credits)
+}
+
+// PutFilmCredits replaces all the credits of a film. The roles and the directors of the film are rebuilt from
+// the acting credits and the directing credits with the "Director" job.
+func PutFilmCredits(c *gin.Context) {
+	id, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		c.IndentedJSON(http.StatusBadRequest, gin.H{"message": "Id is invalid"})
+		return
+	}
+
+	film := FindFilm(bson.M{"_id": id})
+	if len(film.Title) == 0 {
+		c.IndentedJSON(http.StatusNotFound, gin.H{"message": "Film not found"})
+		return
+	}
+
+	var credits []Credit
+	if err := c.BindJSON(&credits); err != nil {
+		c.IndentedJSON(http.StatusBadRequest, gin.H{"message": "JSON is invalid"})
+		return
+	}
+	if valid, msg := AreCreditsValid(credits); !valid {
+		c.IndentedJSON(http.StatusBadRequest, msg)
+		return
+	}
+	sortCredits(credits)
+
+	roles := []Role{}
+	directors := []string{}
+	var crew []string
+	for _, credit := range credits {
+		switch {
+		case credit.Department == actingDepartment:
+			roles = append(roles, Role{Name: credit.Character, ActorId: credit.PersonId})
+		case isLegacyCredit(credit):
+			if !containsString(directors, credit.PersonId) {
+				directors = append(directors, credit.PersonId)
+			}
+		default:
+			if !containsString(crew, credit.PersonId) {
+				crew = append(crew, credit.PersonId)
+			}
+		}
+	}
+	if len(directors) == 0 {
+		c.IndentedJSON(http.StatusBadRequest, gin.H{"message": "A film needs at least one director"})
+		return
+	}
+
+	if _, err := SetFilmCredits(id.Hex(), credits, roles, directors); err != nil {
+		c.IndentedJSON(http.StatusInternalServerError, gin.H{"message": err.Error()})
+		return
+	}
+
+	linkCreditedPeople(c, film, roles, directors, crew)
+
+	c.IndentedJSON(http.StatusNoContent, gin.H{})
+}
+
+// linkCreditedPeople updates the films of the people credited on a film before and after its credits changed, and
+// records the changes of the people in the audit log
+func linkCreditedPeople(c *gin.Context, film Film, roles []Role, directors []string, crew []string) {
+	filmId := []string{film.Id.Hex()}
+
+	var oldActors, newActors, oldCrew []string
+	for _, role := range film.Roles {
+		oldActors = append(oldActors, role.ActorId)
+	}
+	for _, role := range roles {
+		if !containsString(newActors, role.ActorId) {
+			newActors = append(newActors, role.ActorId)
+		}
+	}
+	for _, credit := range film.Credits {
+		if !isLegacyCredit(credit) {
+			oldCrew = append(oldCrew, credit.PersonId)
+		}
+	}
+
+	type link struct {
+		update func(string, []string) (int64, error)
+		people []string
+	}
+	links := []link{
+		{auditedLink(c, "actor", actorColl, RemoveFilmsFromActor), difference(oldActors, newActors)},
+		{auditedLink(c, "actor", actorColl, AddFilmsToActor), difference(newActors, oldActors)},
+		{auditedLink(c, "director", directorColl, RemoveFilmsFromDirector), difference(film.Directors, directors)},
+		{auditedLink(c, "director", directorColl, AddFilmsToDirector), difference(directors, film.Directors)},
+		{auditedLink(c, "person", personColl, RemoveFilmsFromPerson), difference(oldCrew, crew)},
+		{auditedLink(c, "person", personColl, AddFilmsToPerson), difference(crew, oldCrew)},
+	}
+
+	for _, l := range links {
+		for _, person := range l.people {
+			update, person := l.update, person
+			go func() {
+				_, err := update(person, filmId)
+				if err != nil {
+					panic(err)
+				}
+			}()
+		}
+	}
+}
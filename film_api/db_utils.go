@@ -15,5 +15,55 @@ func DeleteItemById(collection *mongo.Collection, idString string) int64 {
 		panic(err)
 	}
 
+	catalogueChanged(catalogueItemType(collection), idString)
+
+	return result.DeletedCount
+}
+
+// DeleteUserItemById deletes an item of the data of the users, like a watched entry, which leaves the catalogue and
+// its caches unchanged
+func DeleteUserItemById(collection *mongo.Collection, idString string) int64 {
+	id, _ := primitive.ObjectIDFromHex(idString)
+
+	result, err := collection.DeleteOne(context.TODO(), bson.M{"_id": id})
+	if err != nil {
+		panic(err)
+	}
+
 	return result.DeletedCount
 }
+
+// notDeleted returns a copy of the filter leaving out the items in the trash, unless it already filters them on
+// their deletion date
+func notDeleted(filter bson.M) bson.M {
+	if _, found := filter["deleted_at"]; found {
+		return filter
+	}
+
+	result := bson.M{"deleted_at": bson.M{"$exists": false}}
+	for key, value := range filter {
+		result[key] = value
+	}
+	return result
+}
+
+// catalogueChanged must be called after every write to the catalogue with the type of the written items, "film" or
+// "person", and their ids, so that the in-memory indexes built from them are refreshed
+func catalogueChanged(itemType string, ids ...string) {
+	catalogueGraph.invalidate()
+	filmSimilarityIndex.invalidate()
+	catalogueStatsCache.invalidate()
+	catalogueAutocompleteIndex.itemsChanged(itemType, ids)
+}
+
+// catalogueItemType returns the type of the items of a collection given to catalogueChanged, which is empty for the
+// collections whose items are not indexed by type
+func catalogueItemType(collection *mongo.Collection) string {
+	switch collection {
+	case filmColl:
+		return "film"
+	case personColl:
+		return "person"
+	}
+	return ""
+}
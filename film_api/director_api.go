@@ -1,7 +1,6 @@
 package film_api
 
 import (
-	"filmflix/db_connection"
 	"github.com/gin-gonic/gin"
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
@@ -10,22 +9,33 @@ import (
 	"strconv"
 )
 
+// Director is the director facet of a person
 type Director struct {
-	Id    primitive.ObjectID `json:"id" bson:"_id,omitempty"`
-	Name  string             `json:"name" bson:"name,omitempty"`
-	Films []string           `json:"films"`
+	Id     primitive.ObjectID `json:"id" bson:"_id,omitempty"`
+	Slug   string             `json:"slug,omitempty" bson:"slug,omitempty"`
+	Name   string             `json:"name" bson:"name,omitempty"`
+	Films  []string           `json:"films" bson:"directed_films"`
+	Facets []string           `json:"facets,omitempty" bson:"facets,omitempty"`
 }
 
 func InitDirectorApiRoutes(apiRoutes *gin.RouterGroup, client *mongo.Client) {
 	InitDirectorCollection(client)
-	directorColl = db_connection.GetCollection(client, "films", "directors")
+	InitRedirectCollection(client)
+	InitSlugHistoryCollection(client)
+	InitAuditCollection(client)
+	InitRevisionCollection(client)
 
 	directorRoutes := apiRoutes.Group("/directors")
+	directorRoutes.Use(ResolveSlug("director"))
+	directorRoutes.Use(Audit("director", directorColl))
 	directorRoutes.GET("/", GetDirectors)
 	directorRoutes.GET("/:id", GetDirectorById)
-	directorRoutes.POST("/", PostDirector)
-	directorRoutes.PATCH("/:id", UpdateDirector)
-	directorRoutes.DELETE("/:id", DeleteDirector)
+	directorRoutes.POST("/", RequireScope(writePeopleScope), PostDirector)
+	directorRoutes.PATCH("/:id", RequireScope(writePeopleScope), UpdateDirector)
+	directorRoutes.DELETE("/:id", RequireScope(writePeopleScope), DeleteDirector)
+	directorRoutes.POST("/:id/merge", RequireScope(writePeopleScope), MergeDirector)
+	directorRoutes.GET("/:id/awards", GetDirectorAwards)
+	initRevisionRoutes(directorRoutes, "person", writePeopleScope)
 }
 
 func GetDirectors(c *gin.Context) {
@@ -41,6 +51,15 @@ func GetDirectors(c *gin.Context) {
 func GetDirectorById(c *gin.Context) {
 	id, _ := primitive.ObjectIDFromHex(c.Param("id"))
 	director := FindDirector(bson.M{"_id": id})
+
+	// The director may have been merged into another one
+	if len(director.Name) == 0 {
+		if target := FindRedirectTarget("director", id.Hex()); len(target) > 0 {
+			targetId, _ := primitive.ObjectIDFromHex(target)
+			director = FindDirector(bson.M{"_id": targetId})
+		}
+	}
+
 	if len(director.Name) > 0 {
 		c.IndentedJSON(http.StatusOK, director)
 	} else {
@@ -49,11 +68,6 @@ func GetDirectorById(c *gin.Context) {
 }
 
 func PostDirector(c *gin.Context) {
-	if !CheckAuthKey(c) {
-		c.IndentedJSON(http.StatusForbidden, gin.H{"message": "Authentication failed"})
-		return
-	}
-
 	var newDirector Director
 	newDirector.Films = []string{}
 
@@ -66,6 +80,10 @@ func PostDirector(c *gin.Context) {
 		return
 	}
 
+	if isDuplicatePerson(c, newDirector.Name) {
+		return
+	}
+
 	newDirector, err := AddDirector(newDirector)
 	if err != nil {
 		c.IndentedJSON(http.StatusBadRequest, gin.H{"message": err})
@@ -86,11 +104,6 @@ func PostDirector(c *gin.Context) {
 }
 
 func UpdateDirector(c *gin.Context) {
-	if !CheckAuthKey(c) {
-		c.IndentedJSON(http.StatusForbidden, gin.H{"message": "Authentication failed"})
-		return
-	}
-
 	var updateData Director
 	idString := c.Param("id")
 
@@ -107,6 +120,15 @@ func UpdateDirector(c *gin.Context) {
 		return
 	}
 
+	updateData.Slug = ""
+	updateData.Facets = nil
+	if len(updateData.Name) > 0 && updateData.Name != oldDirector.Name {
+		if updateData.Slug, err = renameSlug("director", id, oldDirector.Slug, slugify(updateData.Name)); err != nil {
+			c.IndentedJSON(http.StatusInternalServerError, gin.H{"message": err.Error()})
+			return
+		}
+	}
+
 	result := UpdateDirectorById(idString, updateData)
 
 	removedFilms := difference(oldDirector.Films, updateData.Films)
@@ -135,11 +157,6 @@ func UpdateDirector(c *gin.Context) {
 }
 
 func DeleteDirector(c *gin.Context) {
-	if !CheckAuthKey(c) {
-		c.IndentedJSON(http.StatusForbidden, gin.H{"message": "Authentication failed"})
-		return
-	}
-
 	id, err := primitive.ObjectIDFromHex(c.Param("id"))
 	if err != nil {
 		c.IndentedJSON(http.StatusBadRequest, gin.H{"message": err.Error()})
@@ -148,7 +165,23 @@ func DeleteDirector(c *gin.Context) {
 
 	oldDirector := FindDirector(bson.M{"_id": id})
 
-	result := DeleteItemById(directorColl, id.Hex())
+	// The person is put in the trash if it has no other facet, otherwise only the facet is removed
+	if len(oldDirector.Facets) == 1 {
+		result, err := trashPerson(FindPerson(bson.M{"_id": id}))
+		if err != nil {
+			c.IndentedJSON(http.StatusInternalServerError, gin.H{"message": err.Error()})
+			return
+		}
+		if result == 0 {
+			c.IndentedJSON(http.StatusNotModified, gin.H{"message": "No director with the specified id"})
+			return
+		}
+
+		c.IndentedJSON(http.StatusNoContent, result)
+		return
+	}
+
+	result := RemovePersonFacet(id.Hex(), directorFacet)
 
 	if result == 0 {
 		c.IndentedJSON(http.StatusNotModified, gin.H{"message": "No film with the specified id"})
@@ -162,5 +195,47 @@ func DeleteDirector(c *gin.Context) {
 		}
 	}
 
+	if _, err := RemovePersonFromAwards("director", id.Hex()); err != nil {
+		return
+	}
+
 	c.IndentedJSON(http.StatusNoContent, result)
 }
+
+// MergeDirector merges the duplicate director given in the body into the director of the url
+func MergeDirector(c *gin.Context) {
+	var req MergeReq
+	if err := c.BindJSON(&req); err != nil {
+		c.IndentedJSON(http.StatusBadRequest, gin.H{"message": "JSON is invalid"})
+		return
+	}
+
+	id, err := primitive.ObjectIDFromHex(c.Param("id"))
+	duplicateId, duplicateErr := primitive.ObjectIDFromHex(req.Duplicate)
+	if err != nil || duplicateErr != nil || id == duplicateId {
+		c.IndentedJSON(http.StatusBadRequest, gin.H{"message": "Id is invalid"})
+		return
+	}
+
+	target := FindDirector(bson.M{"_id": id})
+	duplicate := FindDirector(bson.M{"_id": duplicateId})
+	if target.Id.IsZero() || duplicate.Id.IsZero() {
+		c.IndentedJSON(http.StatusNotFound, gin.H{"message": "Director not found"})
+		return
+	}
+
+	duplicateDocument := findDocument(directorColl, req.Duplicate)
+	merged, err := MergeDirectors(target, duplicate)
+	if err != nil {
+		c.IndentedJSON(http.StatusInternalServerError, gin.H{"message": err.Error()})
+		return
+	}
+
+	// The Audit middleware records the change of the target, and the duplicate is deleted by the merge
+	if err = newAuditTrail(c).record(http.StatusOK, "director", req.Duplicate, duplicateDocument, nil); err != nil {
+		c.IndentedJSON(http.StatusInternalServerError, gin.H{"message": err.Error()})
+		return
+	}
+
+	c.IndentedJSON(http.StatusOK, merged)
+}
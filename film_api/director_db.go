@@ -2,7 +2,6 @@ package film_api
 
 import (
 	"context"
-	"filmflix/db_connection"
 	"fmt"
 	"github.com/gin-gonic/gin"
 	"go.mongodb.org/mongo-driver/bson"
@@ -13,14 +12,15 @@ import (
 
 var directorColl *mongo.Collection
 
+// InitDirectorCollection initializes the collection of the directors, which are the people having the director facet
 func InitDirectorCollection(client *mongo.Client) {
-	directorColl = db_connection.GetCollection(client, "films", "actors")
+	InitPersonCollection(client)
 }
 
 func FindDirectors(filter bson.M, maxCount int) []Director {
 	var results []Director
 	limit := int64(maxCount)
-	cursor, err := directorColl.Find(context.TODO(), filter, &options.FindOptions{Limit: &limit, Sort: bson.D{{"title", 1}}})
+	cursor, err := directorColl.Find(context.TODO(), notDeleted(withFacet(filter, directorFacet)), &options.FindOptions{Limit: &limit, Sort: bson.D{{"title", 1}}})
 	if err != nil {
 		panic(err)
 	}
@@ -34,7 +34,7 @@ func FindDirectors(filter bson.M, maxCount int) []Director {
 
 func FindDirector(filter bson.M) Director {
 	var director Director
-	err := directorColl.FindOne(context.TODO(), filter).Decode(&director)
+	err := directorColl.FindOne(context.TODO(), notDeleted(withFacet(filter, directorFacet))).Decode(&director)
 
 	if err == mongo.ErrNoDocuments {
 		fmt.Printf("No document was found\n")
@@ -49,23 +49,35 @@ func FindDirector(filter bson.M) Director {
 
 func AddDirector(director Director) (Director, error) {
 	director.Id = primitive.NewObjectID()
+	director.Slug = UniqueSlug("director", slugify(director.Name), director.Id)
+	director.Facets = []string{directorFacet}
+	director.Films = nonNil(director.Films)
 
 	_, err := directorColl.InsertOne(context.TODO(), director)
+	// Another person may have taken the slug since it was chosen
+	if mongo.IsDuplicateKeyError(err) {
+		director.Slug = UniqueSlug("director", slugify(director.Name), director.Id)
+		_, err = directorColl.InsertOne(context.TODO(), director)
+	}
 	if err != nil {
 		return Director{}, err
 	}
 
+	catalogueChanged("person", director.Id.Hex())
+
 	return director, nil
 }
 
 func UpdateDirectorById(idString string, data interface{}) int64 {
 	id, _ := primitive.ObjectIDFromHex(idString)
 
-	result, err := directorColl.UpdateOne(context.TODO(), bson.D{{"_id", id}}, bson.D{{"$set", data}})
+	result, err := directorColl.UpdateOne(context.TODO(), notDeleted(bson.M{"_id": id}), bson.D{{"$set", data}})
 	if err != nil {
 		panic(err)
 	}
 
+	catalogueChanged("person", idString)
+
 	return result.ModifiedCount
 }
 
@@ -78,6 +90,8 @@ func ReplaceDirector(idString string, newDirector Film) int64 {
 		panic(err)
 	}
 
+	catalogueChanged("person", idString)
+
 	return result.ModifiedCount
 }
 
@@ -97,7 +111,8 @@ func AreDirectorsIdsValid(ids []string) (bool, gin.H) {
 		i++
 	}
 
-	result := FindDirectors(bson.M{"_id": bson.M{"$in": directorsIds}}, len(directorsIds))
+	// Any person can be given a new facet by being credited on a film
+	result := FindPeople(bson.M{"_id": bson.M{"$in": directorsIds}}, len(directorsIds))
 
 	if len(result) != len(directorsIds) {
 		return false, gin.H{"message": "At least one director id does not exists"}
@@ -112,11 +127,13 @@ func AddFilmsToDirector(idString string, films []string) (int64, error) {
 		return 0, err
 	}
 
-	result, err := directorColl.UpdateOne(context.TODO(), bson.D{{"_id", id}}, bson.M{"$push": bson.M{"films": bson.M{"$each": films}}})
+	result, err := directorColl.UpdateOne(context.TODO(), bson.D{{"_id", id}}, bson.M{"$push": bson.M{"directed_films": bson.M{"$each": films}}, "$addToSet": bson.M{"facets": directorFacet}})
 	if err != nil {
 		return 0, err
 	}
 
+	catalogueChanged("person", idString)
+
 	return result.ModifiedCount, nil
 }
 
@@ -126,10 +143,24 @@ func RemoveFilmsFromDirector(idString string, films []string) (int64, error) {
 		return 0, err
 	}
 
-	result, err := directorColl.UpdateOne(context.TODO(), bson.M{"_id": id}, bson.M{"$pull": bson.M{"films": bson.M{"$in": films}}})
+	result, err := directorColl.UpdateOne(context.TODO(), bson.M{"_id": id}, bson.M{"$pull": bson.M{"directed_films": bson.M{"$in": films}}})
 	if err != nil {
 		return 0, err
 	}
 
+	catalogueChanged("person", idString)
+
 	return result.ModifiedCount, nil
 }
+
+// MergeDirectors merges the duplicate director into the target one, like MergePeople since they are both people
+func MergeDirectors(target Director, duplicate Director) (Director, error) {
+	targetPerson := FindPerson(bson.M{"_id": target.Id})
+	duplicatePerson := FindPerson(bson.M{"_id": duplicate.Id})
+
+	if _, err := MergePeople(targetPerson, duplicatePerson); err != nil {
+		return Director{}, err
+	}
+
+	return FindDirector(bson.M{"_id": target.Id}), nil
+}
@@ -1,12 +1,15 @@
 package film_api
 
 import (
+	"filmflix/asset_storage"
 	"github.com/gin-gonic/gin"
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
 	"go.mongodb.org/mongo-driver/mongo"
 	"net/http"
 	"strconv"
+	"strings"
+	"time"
 )
 
 type Role struct {
@@ -14,29 +17,79 @@ type Role struct {
 	ActorId string `json:"actor" bson:"actor"`
 }
 
+// LocalizedText is the translation of the title and the description of a film in a language
+type LocalizedText struct {
+	Title       string `bson:"title,omitempty" json:"title"`
+	Description string `bson:"description,omitempty" json:"description"`
+}
+
 type Film struct {
-	Id            primitive.ObjectID `bson:"_id,omitempty" json:"id,omitempty"`
-	Title         string             `bson:"title,omitempty" json:"title"`
-	OriginalTitle string             `bson:"original_title,omitempty" json:"original_title"`
-	Description   string             `bson:"description,omitempty" json:"description"`
-	Directors     []string           `bson:"directors,omitempty" json:"directors"` // Represents the directors ids
-	Poster        string             `bson:"poster,omitempty" json:"poster"`
-	ReleaseDate   string             `bson:"release_date,omitempty" json:"release_date"`
-	Rating        string             `bson:"rt_score,omitempty" json:"rt_score"`
-	Roles         []Role             `bson:"roles,omitempty" json:"roles"`
+	Id                primitive.ObjectID       `bson:"_id,omitempty" json:"id,omitempty"`
+	Slug              string                   `bson:"slug,omitempty" json:"slug,omitempty"`
+	Title             string                   `bson:"title,omitempty" json:"title"`
+	OriginalTitle     string                   `bson:"original_title,omitempty" json:"original_title"`
+	Description       string                   `bson:"description,omitempty" json:"description"`
+	Directors         []string                 `bson:"directors,omitempty" json:"directors"` // Represents the directors ids
+	Poster            string                   `bson:"poster,omitempty" json:"poster"`
+	PosterVariants    map[string]string        `bson:"poster_variants,omitempty" json:"poster_variants,omitempty"` // Maps the name of each resized variant of an uploaded poster, like "small", to its url
+	ReleaseDate       string                   `bson:"release_date,omitempty" json:"release_date"`                 // ReleaseDate is the release date in the ISO 8601 format, up to its precision
+	Release           PartialDate              `bson:"release,omitempty" json:"release"`                           // Release is the parsed release date, set from ReleaseDate
+	RegionalReleases  []RegionalRelease        `bson:"regional_releases,omitempty" json:"regional_releases,omitempty"`
+	Rating            string                   `bson:"rt_score,omitempty" json:"rt_score"`                   // Rating is the critics score, kept for compatibility with the ratings
+	Ratings           map[string]RatingSource  `bson:"ratings,omitempty" json:"ratings,omitempty"`           // Maps each rating source, like "critics" or "users", to the rating it gives
+	RatingScore       float64                  `bson:"rating_score,omitempty" json:"rating_score,omitempty"` // RatingScore is the average of the normalized values of the rating sources
+	Roles             []Role                   `bson:"roles,omitempty" json:"roles"`
+	Credits           []Credit                 `bson:"credits,omitempty" json:"credits"` // Credits are all the people who worked on the film, the actors and directors being listed in the roles and the directors too
+	Genres            []string                 `bson:"genres,omitempty" json:"genres"`   // Represents the genres ids
+	Studios           []StudioCredit           `bson:"studios,omitempty" json:"studios"`
+	UserRatingCount   int                      `bson:"user_rating_count,omitempty" json:"user_rating_count"`
+	UserRatingSum     int                      `bson:"user_rating_sum,omitempty" json:"-"`
+	UserRatingAverage float64                  `bson:"user_rating_average,omitempty" json:"user_rating_average"` // Average of the ratings of the reviews, between 1 and 10
+	Localizations     map[string]LocalizedText `bson:"localizations,omitempty" json:"localizations,omitempty"`   // Maps a lowercase language tag, like "fr" or "ja", to the translations in this language
+	DeletedAt         *time.Time               `bson:"deleted_at,omitempty" json:"deleted_at,omitempty"`         // DeletedAt is set while the film is in the trash
 }
 
 func InitFilmApiRoutes(apiRoutes *gin.RouterGroup, client *mongo.Client) {
 	InitFilmCollection(client)
+	InitSlugHistoryCollection(client)
+	InitReviewCollection(client)
+	InitWatchlistCollections(client)
+	InitAuditCollection(client)
+	InitRevisionCollection(client)
 
 	filmRoutes := apiRoutes.Group("/films")
+	filmRoutes.Use(ResolveSlug("film"))
+	filmRoutes.Use(Audit("film", filmColl))
 	filmRoutes.GET("/", GetFilms)
-	filmRoutes.POST("/", PostFilm)
+	filmRoutes.GET("/top", GetTopRatedFilms)
+	filmRoutes.POST("/", RequireScope(writeFilmsScope), PostFilm)
 	filmRoutes.GET("/:id", GetFilmById)
-	filmRoutes.PATCH("/:id", UpdateFilm)
-	filmRoutes.PATCH("/:id/roles", UpdateRoles)
-	filmRoutes.PATCH("/:id/directors", UpdateDirectors)
-	filmRoutes.DELETE("/:id", DeleteFilm)
+	filmRoutes.GET("/:id/similar", GetSimilarFilms)
+	filmRoutes.GET("/:id/awards", GetFilmAwards)
+	filmRoutes.PATCH("/:id", RequireScope(writeFilmsScope), UpdateFilm)
+	filmRoutes.PATCH("/:id/roles", RequireScope(writeFilmsScope), UpdateRoles)
+	filmRoutes.GET("/:id/credits", GetFilmCredits)
+	filmRoutes.PUT("/:id/credits", RequireScope(writeFilmsScope), PutFilmCredits)
+	filmRoutes.PATCH("/:id/directors", RequireScope(writeFilmsScope), UpdateDirectors)
+	filmRoutes.PATCH("/:id/genres", RequireScope(writeFilmsScope), UpdateGenres)
+	filmRoutes.PATCH("/:id/studios", RequireScope(writeFilmsScope), UpdateStudios)
+	filmRoutes.GET("/:id/localizations", GetLocalizations)
+	filmRoutes.PUT("/:id/localizations/:lang", RequireScope(writeFilmsScope), PutLocalization)
+	filmRoutes.DELETE("/:id/localizations/:lang", RequireScope(writeFilmsScope), DeleteLocalization)
+	filmRoutes.PUT("/:id/poster", RequireScope(writeFilmsScope), PutPoster)
+	filmRoutes.GET("/:id/ratings", GetFilmRatings)
+	filmRoutes.PUT("/:id/ratings/:source", RequireScope(writeFilmsScope), PutFilmRating)
+	filmRoutes.DELETE("/:id/ratings/:source", RequireScope(writeFilmsScope), DeleteFilmRating)
+	filmRoutes.DELETE("/:id/poster", RequireScope(writeFilmsScope), DeletePoster)
+	filmRoutes.DELETE("/:id", RequireScope(writeFilmsScope), DeleteFilm)
+	initRevisionRoutes(filmRoutes, "film", writeFilmsScope)
+
+	// The reviews are written by the users and are not changes of the films, so they are not audited
+	reviewRoutes := apiRoutes.Group("/films/:id/reviews")
+	reviewRoutes.Use(ResolveSlug("film"))
+	reviewRoutes.GET("", GetFilmReviews)
+	reviewRoutes.PUT("", PutReview)
+	reviewRoutes.DELETE("", DeleteReview)
 }
 
 func GetFilms(c *gin.Context) {
@@ -44,37 +97,99 @@ func GetFilms(c *gin.Context) {
 	if l := c.Query("limit"); len(l) > 0 {
 		limit, _ = strconv.Atoi(l)
 	}
-	movies := FindFilms(bson.M{}, limit)
+	filter, ok := filmsFilter(c)
+	if !ok {
+		return
+	}
+
+	sort, ok := filmsSort(c)
+	if !ok {
+		return
+	}
+
+	movies := FindSortedFilms(filter, sort, limit)
+	localizeFilms(c, movies)
+	withCredits(movies)
 
 	c.IndentedJSON(http.StatusOK, movies)
 }
 
 func PostFilm(c *gin.Context) {
-	if !CheckAuthKey(c) {
-		c.IndentedJSON(http.StatusForbidden, gin.H{"message": "Authentication failed"})
-		return
-	}
-
 	var newFilm Film
 	newFilm.Roles = []Role{}
 	newFilm.Directors = []string{}
+	newFilm.Genres = []string{}
+	newFilm.Studios = []StudioCredit{}
 	if err := c.BindJSON(&newFilm); err != nil {
 		return
 	}
 	actorsValid, _ := AreActorsIdsValid(newFilm.Roles)
 	directorsValid, _ := AreDirectorsIdsValid(newFilm.Directors)
+	genresValid, _ := AreGenresIdsValid(newFilm.Genres)
+	studiosValid, _ := AreStudioCreditsValid(newFilm.Studios)
 
-	if !actorsValid || !directorsValid || len(newFilm.Directors) <= 0 || len(newFilm.Title) <= 0 || len(newFilm.ReleaseDate) <= 0 {
+	if !actorsValid || !directorsValid || !genresValid || !studiosValid || len(newFilm.Directors) <= 0 || len(newFilm.Title) <= 0 || len(newFilm.ReleaseDate) <= 0 {
 		c.IndentedJSON(http.StatusBadRequest, gin.H{"message": "Data is invalid, check if required fields are filled and if roles and director ids are valid."})
 		return
 	}
 
+	localizations := make(map[string]LocalizedText, len(newFilm.Localizations))
+	for l, localization := range newFilm.Localizations {
+		normalized, err := normalizeLanguage(l)
+		if err != nil {
+			c.IndentedJSON(http.StatusBadRequest, gin.H{"message": "Language " + l + " is invalid"})
+			return
+		}
+		localizations[normalized] = localization
+	}
+	newFilm.Localizations = localizations
+
+	if valid, msg := normalizeReleases(&newFilm); !valid {
+		c.IndentedJSON(http.StatusBadRequest, msg)
+		return
+	}
+
+	if valid, msg := normalizeRatings(&newFilm); !valid {
+		c.IndentedJSON(http.StatusBadRequest, msg)
+		return
+	}
+
+	// The variants only exist for the uploaded posters
+	newFilm.PosterVariants = nil
+
+	// The credits are set with PUT /api/films/<id>/credits
+	newFilm.Credits = nil
+
+	// The user rating is only computed from the reviews
+	newFilm.UserRatingCount, newFilm.UserRatingSum, newFilm.UserRatingAverage = 0, 0, 0
+
 	newFilm = AddFilm(newFilm)
 
+	addFilmsToDirector := auditedLink(c, "director", directorColl, AddFilmsToDirector)
 	for _, director := range newFilm.Directors {
 		directorInter := director
 		go func() {
-			_, err := AddFilmsToDirector(directorInter, []string{newFilm.Id.Hex()})
+			_, err := addFilmsToDirector(directorInter, []string{newFilm.Id.Hex()})
+			if err != nil {
+				panic(err)
+			}
+		}()
+	}
+
+	for _, genre := range newFilm.Genres {
+		genre := genre
+		go func() {
+			_, err := AddFilmsToGenre(genre, []string{newFilm.Id.Hex()})
+			if err != nil {
+				panic(err)
+			}
+		}()
+	}
+
+	for _, studio := range creditedStudios(newFilm.Studios) {
+		studio := studio
+		go func() {
+			_, err := AddFilmsToStudio(studio, []string{newFilm.Id.Hex()})
 			if err != nil {
 				panic(err)
 			}
@@ -82,10 +197,11 @@ func PostFilm(c *gin.Context) {
 	}
 
 	// Add the actor id to the films he played in
+	addFilmsToActor := auditedLink(c, "actor", actorColl, AddFilmsToActor)
 	for _, role := range newFilm.Roles {
 		roleInter := role
 		go func() {
-			_, err := AddFilmsToActor(roleInter.ActorId, []string{newFilm.Id.Hex()})
+			_, err := addFilmsToActor(roleInter.ActorId, []string{newFilm.Id.Hex()})
 			if err != nil {
 				panic(err)
 			}
@@ -97,11 +213,6 @@ func PostFilm(c *gin.Context) {
 
 // UpdateFilm is used to update all the fields of a film EXCEPT the roles (use UPDATE /api/films/<id>/roles instead) and the directors (use UPDATE /api/films/<id>/roles instead)
 func UpdateFilm(c *gin.Context) {
-	if !CheckAuthKey(c) {
-		c.IndentedJSON(http.StatusForbidden, gin.H{"message": "Authentication failed"})
-		return
-	}
-
 	var updateData Film
 
 	if err := c.BindJSON(&updateData); err != nil {
@@ -111,21 +222,79 @@ func UpdateFilm(c *gin.Context) {
 
 	updateData.Roles = []Role{}
 	updateData.Directors = []string{}
+	updateData.Genres = []string{}
+	updateData.Studios = []StudioCredit{}
+	updateData.Slug = ""
+	updateData.Localizations = nil
+	updateData.PosterVariants = nil
+	updateData.Credits = nil
+	updateData.Release = PartialDate{}
+	updateData.Ratings, updateData.RatingScore = nil, 0
+	if valid, msg := normalizeReleases(&updateData); !valid {
+		c.IndentedJSON(http.StatusBadRequest, msg)
+		return
+	}
+	updateData.UserRatingCount, updateData.UserRatingSum, updateData.UserRatingAverage = 0, 0, 0
+
+	id, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		c.IndentedJSON(http.StatusBadRequest, gin.H{"message": "Id is invalid"})
+		return
+	}
+
+	var critics RatingSource
+	if len(updateData.Rating) > 0 {
+		if critics, err = parseCriticsScore(updateData.Rating); err != nil {
+			c.IndentedJSON(http.StatusBadRequest, gin.H{"message": err.Error()})
+			return
+		}
+		updateData.Rating = formatCriticsScore(critics)
+	}
+
+	// The slug follows the title and the release date of the film
+	if oldFilm := FindFilm(bson.M{"_id": id}); len(oldFilm.Title) > 0 {
+		renamedFilm := oldFilm
+		if len(updateData.Title) > 0 {
+			renamedFilm.Title = updateData.Title
+		}
+		if len(updateData.OriginalTitle) > 0 {
+			renamedFilm.OriginalTitle = updateData.OriginalTitle
+		}
+		if len(updateData.ReleaseDate) > 0 {
+			renamedFilm.ReleaseDate, renamedFilm.Release = updateData.ReleaseDate, updateData.Release
+		}
+		if updateData.Slug, err = renameSlug("film", id, oldFilm.Slug, filmSlugBase(renamedFilm)); err != nil {
+			c.IndentedJSON(http.StatusInternalServerError, gin.H{"message": err.Error()})
+			return
+		}
+	}
 
 	if _, err := UpdateFilmById(c.Param("id"), updateData); err != nil {
 		c.IndentedJSON(http.StatusBadRequest, gin.H{"message": err.Error()})
 		return
 	}
 
+	// The rt_score is the critics rating
+	if len(updateData.Rating) > 0 {
+		if _, err := SetFilmRating(id.Hex(), criticsRating, &critics); err != nil {
+			c.IndentedJSON(http.StatusInternalServerError, gin.H{"message": err.Error()})
+			return
+		}
+	}
+
+	// A poster given as an url replaces the uploaded one
+	if len(updateData.Poster) > 0 {
+		if err := removeStoredPoster(id.Hex(), updateData.Poster); err != nil {
+			c.IndentedJSON(http.StatusInternalServerError, gin.H{"message": err.Error()})
+			return
+		}
+	}
+
 	c.IndentedJSON(http.StatusNoContent, gin.H{})
 }
 
+// DeleteFilm puts a film in the trash and removes it from its people, genres and studios
 func DeleteFilm(c *gin.Context) {
-	if !CheckAuthKey(c) {
-		c.IndentedJSON(http.StatusForbidden, gin.H{"message": "Authentication failed"})
-		return
-	}
-
 	id := c.Param("id")
 	filmIdObj, err := primitive.ObjectIDFromHex(id)
 
@@ -136,27 +305,64 @@ func DeleteFilm(c *gin.Context) {
 
 	film := FindFilm(bson.M{"_id": filmIdObj})
 
+	removeFilmsFromDirector := auditedLink(c, "director", directorColl, RemoveFilmsFromDirector)
 	for _, director := range film.Directors {
 		director := director
 		go func() {
-			_, err := RemoveFilmsFromDirector(director, []string{id})
+			_, err := removeFilmsFromDirector(director, []string{id})
 			if err != nil {
 				panic(err)
 			}
 		}()
 	}
 
+	removeFilmsFromActor := auditedLink(c, "actor", actorColl, RemoveFilmsFromActor)
 	for _, role := range film.Roles {
 		role := role
 		go func() {
-			_, err := RemoveFilmsFromActor(role.ActorId, []string{id})
+			_, err := removeFilmsFromActor(role.ActorId, []string{id})
+			if err != nil {
+				panic(err)
+			}
+		}()
+	}
+
+	for _, genre := range film.Genres {
+		genre := genre
+		go func() {
+			_, err := RemoveFilmsFromGenre(genre, []string{id})
+			if err != nil {
+				panic(err)
+			}
+		}()
+	}
+
+	for _, studio := range creditedStudios(film.Studios) {
+		studio := studio
+		go func() {
+			_, err := RemoveFilmsFromStudio(studio, []string{id})
+			if err != nil {
+				panic(err)
+			}
+		}()
+	}
+
+	removeFilmsFromPerson := auditedLink(c, "person", personColl, RemoveFilmsFromPerson)
+	for _, credit := range film.Credits {
+		if isLegacyCredit(credit) {
+			continue
+		}
+		person := credit.PersonId
+		go func() {
+			_, err := removeFilmsFromPerson(person, []string{id})
 			if err != nil {
 				panic(err)
 			}
 		}()
 	}
 
-	result := DeleteItemById(filmColl, id)
+	// The awards, the reviews, the user lists entries and the poster are only removed when the film is purged
+	result := TrashItemById(filmColl, id, nil)
 
 	c.IndentedJSON(http.StatusNoContent, result)
 }
@@ -172,24 +378,65 @@ func GetFilmById(c *gin.Context) {
 	film := FindFilm(bson.M{"_id": id})
 
 	if film.Title != "" {
-		c.IndentedJSON(http.StatusOK, film)
+		films := []Film{film}
+		localizeFilms(c, films)
+		withCredits(films)
+		c.IndentedJSON(http.StatusOK, films[0])
 		return
 	}
 
 	c.IndentedJSON(http.StatusNotFound, gin.H{"message": "Film not found"})
 }
 
+// GetSimilarFilms returns the films ranked by their similarity with the given film
+func GetSimilarFilms(c *gin.Context) {
+	id, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		c.IndentedJSON(http.StatusBadRequest, gin.H{"message": "Id is invalid"})
+		return
+	}
+
+	limit := 10
+	if l := c.Query("limit"); len(l) > 0 {
+		limit, _ = strconv.Atoi(l)
+	}
+
+	ids, scores := filmSimilarityIndex.similarFilms(id.Hex())
+	if ids == nil && len(FindFilm(bson.M{"_id": id}).Title) == 0 {
+		c.IndentedJSON(http.StatusNotFound, gin.H{"message": "Film not found"})
+		return
+	}
+	if limit > 0 && len(ids) > limit {
+		ids = ids[:limit]
+	}
+
+	filmsIds := make([]primitive.ObjectID, len(ids))
+	for i, filmId := range ids {
+		filmsIds[i], _ = primitive.ObjectIDFromHex(filmId)
+	}
+	films := make(map[string]Film, len(ids))
+	for _, film := range FindFilms(bson.M{"_id": bson.M{"$in": filmsIds}}, len(filmsIds)) {
+		films[film.Id.Hex()] = film
+	}
+
+	results := make([]SimilarFilm, 0, len(ids))
+	languages := requestedLanguages(c)
+	for _, filmId := range ids {
+		if film, found := films[filmId]; found {
+			localizeFilm(&film, languages)
+			results = append(results, SimilarFilm{Film: film, Score: scores[filmId]})
+		}
+	}
+
+	c.IndentedJSON(http.StatusOK, results)
+}
+
 type UpdateRolesReq struct {
 	Replace bool   `json:"replace"`
 	Roles   []Role `json:"roles"`
 }
 
 func UpdateRoles(c *gin.Context) {
-	if !CheckAuthKey(c) {
-		c.IndentedJSON(http.StatusForbidden, gin.H{"message": "Authentication failed"})
-		return
-	}
-
 	var req UpdateRolesReq
 
 	if err := c.BindJSON(&req); err != nil {
@@ -202,21 +449,23 @@ func UpdateRoles(c *gin.Context) {
 		return
 	}
 
+	if _, err := UpdateFilmById(c.Param("id"), Film{Roles: req.Roles}); err != nil {
+		c.IndentedJSON(http.StatusBadRequest, gin.H{"message": err.Error()})
+		return
+	}
+
+	id := c.Param("id")
+	addFilmsToActor := auditedLink(c, "actor", actorColl, AddFilmsToActor)
 	for _, role := range req.Roles {
 		roleInter := role
 		go func() {
-			_, err := AddFilmsToActor(roleInter.ActorId, []string{c.Param("id")})
+			_, err := addFilmsToActor(roleInter.ActorId, []string{id})
 			if err != nil {
 				panic(err)
 			}
 		}()
 	}
 
-	if _, err := UpdateFilmById(c.Param("id"), Film{Roles: req.Roles}); err != nil {
-		c.IndentedJSON(http.StatusBadRequest, gin.H{"message": err.Error()})
-		return
-	}
-
 	c.IndentedJSON(http.StatusNoContent, gin.H{})
 }
 
@@ -226,11 +475,6 @@ type UpdateDirectorsReq struct {
 }
 
 func UpdateDirectors(c *gin.Context) {
-	if !CheckAuthKey(c) {
-		c.IndentedJSON(http.StatusForbidden, gin.H{"message": "Authentication failed"})
-		return
-	}
-
 	var req UpdateDirectorsReq
 
 	if err := c.BindJSON(&req); err != nil {
@@ -243,20 +487,359 @@ func UpdateDirectors(c *gin.Context) {
 		return
 	}
 
+	if _, err := UpdateFilmById(c.Param("id"), Film{Directors: req.Directors}); err != nil {
+		c.IndentedJSON(http.StatusBadRequest, gin.H{"message": err.Error()})
+		return
+	}
+
+	id := c.Param("id")
+	addFilmsToDirector := auditedLink(c, "director", directorColl, AddFilmsToDirector)
 	for _, director := range req.Directors {
 		director := director
 		go func() {
-			_, err := AddFilmsToDirector(director, []string{c.Param("id")})
+			_, err := addFilmsToDirector(director, []string{id})
 			if err != nil {
 				panic(err)
 			}
 		}()
 	}
 
-	if _, err := UpdateFilmById(c.Param("id"), Film{Directors: req.Directors}); err != nil {
+	c.IndentedJSON(http.StatusNoContent, gin.H{})
+}
+
+type UpdateGenresReq struct {
+	Genres []string `json:"genres"`
+}
+
+// UpdateGenres replaces the genres of a film, and updates the films of the added and removed genres
+func UpdateGenres(c *gin.Context) {
+	var req UpdateGenresReq
+
+	if err := c.BindJSON(&req); err != nil {
+		c.IndentedJSON(http.StatusBadRequest, gin.H{"message": "JSON is invalid"})
+		return
+	}
+
+	if valid, msg := AreGenresIdsValid(req.Genres); !valid {
+		c.IndentedJSON(http.StatusBadRequest, msg)
+		return
+	}
+
+	id, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		c.IndentedJSON(http.StatusBadRequest, gin.H{"message": "Id is invalid"})
+		return
+	}
+	oldFilm := FindFilm(bson.M{"_id": id})
+	if req.Genres == nil {
+		req.Genres = []string{}
+	}
+
+	if _, err := UpdateFilmById(c.Param("id"), bson.M{"genres": req.Genres}); err != nil {
 		c.IndentedJSON(http.StatusBadRequest, gin.H{"message": err.Error()})
 		return
 	}
 
+	// The context is reused by gin once the handler returns, so the goroutines are given the film id
+	filmId := id.Hex()
+	for _, removed := range difference(oldFilm.Genres, req.Genres) {
+		go func(genre string) {
+			_, err := RemoveFilmsFromGenre(genre, []string{filmId})
+			if err != nil {
+				panic(err)
+			}
+		}(removed)
+	}
+
+	for _, added := range difference(req.Genres, oldFilm.Genres) {
+		go func(genre string) {
+			_, err := AddFilmsToGenre(genre, []string{filmId})
+			if err != nil {
+				panic(err)
+			}
+		}(added)
+	}
+
+	c.IndentedJSON(http.StatusNoContent, gin.H{})
+}
+
+type UpdateStudiosReq struct {
+	Studios []StudioCredit `json:"studios"`
+}
+
+// UpdateStudios replaces the studios credited on a film, and updates the films of the added and removed studios
+func UpdateStudios(c *gin.Context) {
+	var req UpdateStudiosReq
+
+	if err := c.BindJSON(&req); err != nil {
+		c.IndentedJSON(http.StatusBadRequest, gin.H{"message": "JSON is invalid"})
+		return
+	}
+
+	if valid, msg := AreStudioCreditsValid(req.Studios); !valid {
+		c.IndentedJSON(http.StatusBadRequest, msg)
+		return
+	}
+
+	id, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		c.IndentedJSON(http.StatusBadRequest, gin.H{"message": "Id is invalid"})
+		return
+	}
+	oldFilm := FindFilm(bson.M{"_id": id})
+	if req.Studios == nil {
+		req.Studios = []StudioCredit{}
+	}
+
+	if _, err := UpdateFilmById(c.Param("id"), bson.M{"studios": req.Studios}); err != nil {
+		c.IndentedJSON(http.StatusBadRequest, gin.H{"message": err.Error()})
+		return
+	}
+
+	// The context is reused by gin once the handler returns, so the goroutines are given the film id
+	filmId := id.Hex()
+	oldStudios, newStudios := creditedStudios(oldFilm.Studios), creditedStudios(req.Studios)
+	for _, removed := range difference(oldStudios, newStudios) {
+		go func(studio string) {
+			_, err := RemoveFilmsFromStudio(studio, []string{filmId})
+			if err != nil {
+				panic(err)
+			}
+		}(removed)
+	}
+
+	for _, added := range difference(newStudios, oldStudios) {
+		go func(studio string) {
+			_, err := AddFilmsToStudio(studio, []string{filmId})
+			if err != nil {
+				panic(err)
+			}
+		}(added)
+	}
+
 	c.IndentedJSON(http.StatusNoContent, gin.H{})
 }
+
+// filmsFilter returns the filter matching the films selected by the query params of the request.
+// If a param is invalid, it answers with an error and returns false.
+func filmsFilter(c *gin.Context) (bson.M, bool) {
+	filter := bson.M{}
+
+	// genre is a comma separated list of genres ids or slugs, the films must have all of them
+	if g := c.Query("genre"); len(g) > 0 {
+		var genres []string
+		for _, genre := range strings.Split(g, ",") {
+			genre = strings.TrimSpace(genre)
+			if !primitive.IsValidObjectID(genre) {
+				id := FindIdBySlug("genre", genre)
+				if id.IsZero() {
+					c.IndentedJSON(http.StatusBadRequest, gin.H{"message": "Genre " + genre + " does not exist"})
+					return nil, false
+				}
+				genre = id.Hex()
+			}
+			genres = append(genres, genre)
+		}
+		filter["genres"] = bson.M{"$all": genres}
+	}
+
+	// studio is the id or the slug of a studio which worked on the films
+	if s := c.Query("studio"); len(s) > 0 {
+		if !primitive.IsValidObjectID(s) {
+			id := FindIdBySlug("studio", s)
+			if id.IsZero() {
+				c.IndentedJSON(http.StatusBadRequest, gin.H{"message": "Studio " + s + " does not exist"})
+				return nil, false
+			}
+			s = id.Hex()
+		}
+		filter["studios.studio"] = s
+	}
+
+	// awarded is either "won", to get the award-winning films, or "nominated", to get the films at least nominated
+	if a := c.Query("awarded"); len(a) > 0 {
+		if a != "won" && a != "nominated" {
+			c.IndentedJSON(http.StatusBadRequest, gin.H{"message": "awarded must be either won or nominated"})
+			return nil, false
+		}
+		filter["_id"] = bson.M{"$in": FindAwardedFilmsIds(a == "won")}
+	}
+
+	if !ratingsFilter(c, filter) {
+		return nil, false
+	}
+
+	// year is a release year, like 1988, or a range of release years, like 1980-1989
+	if y := c.Query("year"); len(y) > 0 {
+		from, to, err := parseYearRange(y)
+		if err != nil {
+			c.IndentedJSON(http.StatusBadRequest, gin.H{"message": "year must be a year or a range of years like 1980-1989"})
+			return nil, false
+		}
+		filter["release.date"] = bson.M{
+			"$gte": time.Date(from, time.January, 1, 0, 0, 0, 0, time.UTC),
+			"$lt":  time.Date(to+1, time.January, 1, 0, 0, 0, 0, time.UTC),
+		}
+	}
+
+	return filter, true
+}
+
+// filmsSort returns the order of the films asked with ?sort=<field>, the field being prefixed by "-" for a descending order.
+// The films are sorted by title by default.
+func filmsSort(c *gin.Context) (bson.D, bool) {
+	field := c.DefaultQuery("sort", "title")
+	order := 1
+	if strings.HasPrefix(field, "-") {
+		field, order = field[1:], -1
+	}
+
+	switch field {
+	case "title":
+		return bson.D{{Key: "title", Value: order}}, true
+	case "release":
+		return bson.D{{Key: "release.date", Value: order}, {Key: "title", Value: 1}}, true
+	case "rating":
+		return bson.D{{Key: "rating_score", Value: order}, {Key: "title", Value: 1}}, true
+	}
+
+	// The films can be sorted by the normalized value of a rating source, like "-critics"
+	if containsString(ratingSources, field) {
+		return bson.D{{Key: "ratings." + field + ".normalized", Value: order}, {Key: "title", Value: 1}}, true
+	}
+
+	c.IndentedJSON(http.StatusBadRequest, gin.H{"message": "sort must be title, release, rating or a rating source"})
+	return nil, false
+}
+
+func GetLocalizations(c *gin.Context) {
+	id, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		c.IndentedJSON(http.StatusBadRequest, gin.H{"message": "Id is invalid"})
+		return
+	}
+
+	film := FindFilm(bson.M{"_id": id})
+	if film.Title == "" {
+		c.IndentedJSON(http.StatusNotFound, gin.H{"message": "Film not found"})
+		return
+	}
+
+	if film.Localizations == nil {
+		film.Localizations = map[string]LocalizedText{}
+	}
+	c.IndentedJSON(http.StatusOK, film.Localizations)
+}
+
+// PutLocalization sets the title and the description of a film in the language of the url
+func PutLocalization(c *gin.Context) {
+	lang, err := normalizeLanguage(c.Param("lang"))
+	if err != nil {
+		c.IndentedJSON(http.StatusBadRequest, gin.H{"message": "Language is invalid"})
+		return
+	}
+
+	var localization LocalizedText
+	if err := c.BindJSON(&localization); err != nil {
+		c.IndentedJSON(http.StatusBadRequest, gin.H{"message": "JSON is invalid"})
+		return
+	}
+	if len(localization.Title) == 0 && len(localization.Description) == 0 {
+		c.IndentedJSON(http.StatusBadRequest, gin.H{"message": "The title or the description is required"})
+		return
+	}
+
+	result, err := SetFilmLocalization(c.Param("id"), lang, localization)
+	if err != nil {
+		c.IndentedJSON(http.StatusBadRequest, gin.H{"message": err.Error()})
+		return
+	}
+
+	c.IndentedJSON(http.StatusNoContent, result)
+}
+
+func DeleteLocalization(c *gin.Context) {
+	lang, err := normalizeLanguage(c.Param("lang"))
+	if err != nil {
+		c.IndentedJSON(http.StatusBadRequest, gin.H{"message": "Language is invalid"})
+		return
+	}
+
+	result, err := RemoveFilmLocalization(c.Param("id"), lang)
+	if err != nil {
+		c.IndentedJSON(http.StatusBadRequest, gin.H{"message": err.Error()})
+		return
+	}
+
+	c.IndentedJSON(http.StatusNoContent, result)
+}
+
+// PutPoster stores an uploaded JPEG or PNG poster with its resized variants, and makes it the poster of the film
+func PutPoster(c *gin.Context) {
+	id, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		c.IndentedJSON(http.StatusBadRequest, gin.H{"message": "Id is invalid"})
+		return
+	}
+	if film := FindFilm(bson.M{"_id": id}); film.Id.IsZero() {
+		c.IndentedJSON(http.StatusNotFound, gin.H{"message": "Film not found"})
+		return
+	}
+
+	data, err := readPoster(c)
+	if err != nil {
+		c.IndentedJSON(http.StatusBadRequest, gin.H{"message": err.Error()})
+		return
+	}
+
+	files, urls, err := posterFiles(id.Hex(), data)
+	if err != nil {
+		c.IndentedJSON(http.StatusBadRequest, gin.H{"message": err.Error()})
+		return
+	}
+
+	// The previous poster is removed first, its files may have the same names if the same poster is uploaded again
+	storage := asset_storage.GetStorage()
+	if err := storage.RemoveAll(posterDirectory(id.Hex())); err != nil {
+		c.IndentedJSON(http.StatusInternalServerError, gin.H{"message": err.Error()})
+		return
+	}
+	for _, file := range files {
+		if err := storage.Save(file.name, file.data); err != nil {
+			c.IndentedJSON(http.StatusInternalServerError, gin.H{"message": err.Error()})
+			return
+		}
+	}
+
+	if _, err := SetFilmPoster(id.Hex(), urls[originalPosterVariant], urls); err != nil {
+		c.IndentedJSON(http.StatusInternalServerError, gin.H{"message": err.Error()})
+		return
+	}
+
+	c.IndentedJSON(http.StatusOK, gin.H{"poster": urls[originalPosterVariant], "poster_variants": urls})
+}
+
+func DeletePoster(c *gin.Context) {
+	id, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		c.IndentedJSON(http.StatusBadRequest, gin.H{"message": "Id is invalid"})
+		return
+	}
+
+	if err := removeStoredPoster(id.Hex(), ""); err != nil {
+		c.IndentedJSON(http.StatusInternalServerError, gin.H{"message": err.Error()})
+		return
+	}
+
+	c.IndentedJSON(http.StatusNoContent, gin.H{})
+}
+
+// removeStoredPoster removes the uploaded poster of a film and replaces it with the given url, which may be empty
+func removeStoredPoster(filmId string, poster string) error {
+	if err := asset_storage.GetStorage().RemoveAll(posterDirectory(filmId)); err != nil {
+		return err
+	}
+
+	_, err := SetFilmPoster(filmId, poster, nil)
+	return err
+}
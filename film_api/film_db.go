@@ -9,17 +9,19 @@ import (
 	"go.mongodb.org/mongo-driver/bson/primitive"
 	"go.mongodb.org/mongo-driver/mongo"
 	"go.mongodb.org/mongo-driver/mongo/options"
+	"time"
 )
 
 var filmColl *mongo.Collection
 
 func InitFilmCollection(client *mongo.Client) {
 	filmColl = db_connection.GetCollection(client, "films", "films")
+	createSlugIndex(filmColl)
 }
 
 func FindFilm(filter bson.M) Film {
 	var film Film
-	err := filmColl.FindOne(context.TODO(), filter).Decode(&film)
+	err := filmColl.FindOne(context.TODO(), notDeleted(filter)).Decode(&film)
 
 	if err == mongo.ErrNoDocuments {
 		fmt.Printf("No document was found\n")
@@ -36,9 +38,14 @@ func FindFilm(filter bson.M) Film {
 
 // FindFilms retrieves a given amount of films from the given collection
 func FindFilms(filter bson.M, maxCount int) []Film {
+	return FindSortedFilms(filter, bson.D{{Key: "title", Value: 1}}, maxCount)
+}
+
+// FindSortedFilms retrieves a given amount of films in the given order
+func FindSortedFilms(filter bson.M, sort bson.D, maxCount int) []Film {
 	var results []Film
 	limit := int64(maxCount)
-	cursor, err := filmColl.Find(context.TODO(), filter, &options.FindOptions{Limit: &limit, Sort: bson.D{{"title", 1}}})
+	cursor, err := filmColl.Find(context.TODO(), notDeleted(filter), &options.FindOptions{Limit: &limit, Sort: sort})
 
 	if err != nil {
 		panic(err)
@@ -54,12 +61,20 @@ func FindFilms(filter bson.M, maxCount int) []Film {
 // AddFilm adds a film to the given collection and return the film with its id
 func AddFilm(film Film) Film {
 	film.Id = primitive.NewObjectID()
+	film.Slug = UniqueSlug("film", filmSlugBase(film), film.Id)
 
 	_, err := filmColl.InsertOne(context.TODO(), film)
+	// Another film may have taken the slug since it was chosen
+	if mongo.IsDuplicateKeyError(err) {
+		film.Slug = UniqueSlug("film", filmSlugBase(film), film.Id)
+		_, err = filmColl.InsertOne(context.TODO(), film)
+	}
 	if err != nil {
 		panic(err)
 	}
 
+	catalogueChanged("film", film.Id.Hex())
+
 	return film
 }
 
@@ -67,11 +82,13 @@ func AddFilm(film Film) Film {
 func UpdateFilmById(idString string, data interface{}) (int64, error) {
 	id, _ := primitive.ObjectIDFromHex(idString)
 
-	result, err := filmColl.UpdateOne(context.TODO(), bson.D{{"_id", id}}, bson.D{{"$set", data}})
+	result, err := filmColl.UpdateOne(context.TODO(), notDeleted(bson.M{"_id": id}), bson.D{{"$set", data}})
 	if err != nil {
 		return 0, err
 	}
 
+	catalogueChanged("film", idString)
+
 	return result.ModifiedCount, nil
 }
 
@@ -85,6 +102,8 @@ func ReplaceFilm(idString string, newFilm Film) int64 {
 		panic(err)
 	}
 
+	catalogueChanged("film", idString)
+
 	return result.ModifiedCount
 }
 
@@ -100,6 +119,8 @@ func AddRoles(idString string, roles []Role) (int64, error) {
 		return 0, err
 	}
 
+	catalogueChanged("film", idString)
+
 	return result.ModifiedCount, nil
 }
 
@@ -115,6 +136,8 @@ func AddActorsToFilm(idString string, actors []Role) (int64, error) {
 		return 0, err
 	}
 
+	catalogueChanged("film", idString)
+
 	return result.ModifiedCount, nil
 }
 
@@ -129,6 +152,8 @@ func RemoveActorsFromFilm(idString string, actorsIds []string) (int64, error) {
 		return 0, err
 	}
 
+	catalogueChanged("film", idString)
+
 	return result.ModifiedCount, err
 }
 func AddDirectorsToFilm(idString string, directors []string) (int64, error) {
@@ -143,6 +168,8 @@ func AddDirectorsToFilm(idString string, directors []string) (int64, error) {
 		return 0, err
 	}
 
+	catalogueChanged("film", idString)
+
 	return result.ModifiedCount, nil
 }
 
@@ -157,9 +184,119 @@ func RemoveDirectorsFromFilm(idString string, directorsIds []string) (int64, err
 		return 0, err
 	}
 
+	catalogueChanged("film", idString)
+
 	return result.ModifiedCount, err
 }
 
+// SetFilmLocalization sets the translations of a film in the given language and returns the number of modified items
+func SetFilmLocalization(idString string, lang string, localization LocalizedText) (int64, error) {
+	id, err := primitive.ObjectIDFromHex(idString)
+	if err != nil {
+		return 0, err
+	}
+
+	result, err := filmColl.UpdateOne(context.TODO(), bson.M{"_id": id}, bson.M{"$set": bson.M{"localizations." + lang: localization}})
+	if err != nil {
+		return 0, err
+	}
+
+	catalogueChanged("film", idString)
+
+	return result.ModifiedCount, nil
+}
+
+// RemoveFilmLocalization removes the translations of a film in the given language and returns the number of modified items
+func RemoveFilmLocalization(idString string, lang string) (int64, error) {
+	id, err := primitive.ObjectIDFromHex(idString)
+	if err != nil {
+		return 0, err
+	}
+
+	result, err := filmColl.UpdateOne(context.TODO(), bson.M{"_id": id}, bson.M{"$unset": bson.M{"localizations." + lang: ""}})
+	if err != nil {
+		return 0, err
+	}
+
+	catalogueChanged("film", idString)
+
+	return result.ModifiedCount, nil
+}
+
+// SetFilmPoster sets the url of the poster of a film and of its resized variants, and returns the number of modified items.
+// Without variants, the variants of the previous poster are removed.
+func SetFilmPoster(idString string, poster string, variants map[string]string) (int64, error) {
+	id, err := primitive.ObjectIDFromHex(idString)
+	if err != nil {
+		return 0, err
+	}
+
+	update := bson.M{"$set": bson.M{"poster": poster, "poster_variants": variants}}
+	if len(variants) == 0 {
+		update = bson.M{"$set": bson.M{"poster": poster}, "$unset": bson.M{"poster_variants": ""}}
+	}
+	if len(poster) == 0 {
+		update = bson.M{"$unset": bson.M{"poster": "", "poster_variants": ""}}
+	}
+
+	result, err := filmColl.UpdateOne(context.TODO(), bson.M{"_id": id}, update)
+	if err != nil {
+		return 0, err
+	}
+
+	catalogueChanged("film", idString)
+
+	return result.ModifiedCount, nil
+}
+
+func AddGenresToFilm(idString string, genres []string) (int64, error) {
+	id, err := primitive.ObjectIDFromHex(idString)
+	if err != nil {
+		return 0, err
+	}
+
+	result, err := filmColl.UpdateOne(context.TODO(), bson.M{"_id": id}, bson.M{"$addToSet": bson.M{"genres": bson.M{"$each": genres}}})
+	if err != nil {
+		return 0, err
+	}
+
+	catalogueChanged("film", idString)
+
+	return result.ModifiedCount, nil
+}
+
+func RemoveGenresFromFilm(idString string, genresIds []string) (int64, error) {
+	id, err := primitive.ObjectIDFromHex(idString)
+	if err != nil {
+		return 0, err
+	}
+
+	result, err := filmColl.UpdateOne(context.TODO(), bson.M{"_id": id}, bson.M{"$pull": bson.M{"genres": bson.M{"$in": genresIds}}})
+	if err != nil {
+		return 0, err
+	}
+
+	catalogueChanged("film", idString)
+
+	return result.ModifiedCount, nil
+}
+
+func RemoveStudiosFromFilm(idString string, studiosIds []string) (int64, error) {
+	id, err := primitive.ObjectIDFromHex(idString)
+	if err != nil {
+		return 0, err
+	}
+
+	result, err := filmColl.UpdateOne(context.TODO(), bson.M{"_id": id}, bson.M{"$pull": bson.M{"studios": bson.M{"studio": bson.M{"$in": studiosIds}}}})
+	if err != nil {
+		return 0, err
+	}
+
+	catalogueChanged("film", idString)
+
+	return result.ModifiedCount, nil
+}
+
 func AreFilmsIdsValid(ids []string) (bool, gin.H) {
 	tempIds := make(map[string]struct{})
 	for i, id := range ids {
@@ -184,3 +321,162 @@ func AreFilmsIdsValid(ids []string) (bool, gin.H) {
 
 	return true, nil
 }
+
+// ReplaceActorInRoles makes all the roles played by an actor point to another one and returns the number of modified films
+func ReplaceActorInRoles(oldActorId string, newActorId string) (int64, error) {
+	result, err := filmColl.UpdateMany(context.TODO(),
+		bson.M{"roles.actor": oldActorId},
+		bson.M{"$set": bson.M{"roles.$[role].actor": newActorId}},
+		options.Update().SetArrayFilters(options.ArrayFilters{Filters: []interface{}{bson.M{"role.actor": oldActorId}}}),
+	)
+	if err != nil {
+		return 0, err
+	}
+
+	if _, err := replacePersonInCredits(oldActorId, newActorId); err != nil {
+		return 0, err
+	}
+
+	catalogueChanged("person", oldActorId, newActorId)
+
+	return result.ModifiedCount, nil
+}
+
+// ReplaceDirectorInFilms makes all the films directed by a director point to another one and returns the number of modified films
+func ReplaceDirectorInFilms(oldDirectorId string, newDirectorId string) (int64, error) {
+	// The films already listing both directors must not list the new one twice
+	_, err := filmColl.UpdateMany(context.TODO(),
+		bson.M{"directors": bson.M{"$all": []string{oldDirectorId, newDirectorId}}},
+		bson.M{"$pull": bson.M{"directors": oldDirectorId}},
+	)
+	if err != nil {
+		return 0, err
+	}
+
+	result, err := filmColl.UpdateMany(context.TODO(),
+		bson.M{"directors": oldDirectorId},
+		bson.M{"$set": bson.M{"directors.$[director]": newDirectorId}},
+		options.Update().SetArrayFilters(options.ArrayFilters{Filters: []interface{}{bson.M{"director": oldDirectorId}}}),
+	)
+	if err != nil {
+		return 0, err
+	}
+
+	if _, err := replacePersonInCredits(oldDirectorId, newDirectorId); err != nil {
+		return 0, err
+	}
+
+	catalogueChanged("person", oldDirectorId, newDirectorId)
+
+	return result.ModifiedCount, nil
+}
+
+// SetFilmCredits replaces the credits of a film with the roles and the directors matching them, and returns the number of modified items
+func SetFilmCredits(idString string, credits []Credit, roles []Role, directors []string) (int64, error) {
+	id, err := primitive.ObjectIDFromHex(idString)
+	if err != nil {
+		return 0, err
+	}
+
+	result, err := filmColl.UpdateOne(context.TODO(), bson.M{"_id": id}, bson.M{"$set": bson.M{"credits": credits, "roles": roles, "directors": directors}})
+	if err != nil {
+		return 0, err
+	}
+
+	catalogueChanged("film", idString)
+
+	return result.ModifiedCount, nil
+}
+
+// RemovePersonFromCredits removes all the credits of a person and returns the number of modified films
+func RemovePersonFromCredits(personId string) (int64, error) {
+	result, err := filmColl.UpdateMany(context.TODO(), bson.M{"credits.person": personId}, bson.M{"$pull": bson.M{"credits": bson.M{"person": personId}}})
+	if err != nil {
+		return 0, err
+	}
+
+	catalogueChanged("person", personId)
+
+	return result.ModifiedCount, nil
+}
+
+// replacePersonInCredits makes all the credits of a person point to another one and returns the number of modified films
+func replacePersonInCredits(oldPersonId string, newPersonId string) (int64, error) {
+	result, err := filmColl.UpdateMany(context.TODO(),
+		bson.M{"credits.person": oldPersonId},
+		bson.M{"$set": bson.M{"credits.$[credit].person": newPersonId}},
+		options.Update().SetArrayFilters(options.ArrayFilters{Filters: []interface{}{bson.M{"credit.person": oldPersonId}}}),
+	)
+	if err != nil {
+		return 0, err
+	}
+
+	return result.ModifiedCount, nil
+}
+
+// BackfillReleaseDates parses the release dates of the films created before they were parsed.
+// The dates which can't be parsed are kept up to their year, when they contain one.
+func BackfillReleaseDates() {
+	for _, film := range FindFilms(bson.M{"release": bson.M{"$exists": false}, "release_date": bson.M{"$exists": true}}, 0) {
+		release, err := parsePartialDate(film.ReleaseDate)
+		if err != nil {
+			year := parseReleaseYear(film.ReleaseDate)
+			if year == 0 {
+				fmt.Printf("The release date of the film %v can't be parsed\n", film.Id.Hex())
+				continue
+			}
+			release = newPartialDate(time.Date(year, time.January, 1, 0, 0, 0, 0, time.UTC), yearPrecision)
+		}
+
+		if _, err := UpdateFilmById(film.Id.Hex(), bson.M{"release": release}); err != nil {
+			panic(err)
+		}
+	}
+}
+
+// SetFilmRating sets a rating source of a film, or removes it if the rating is nil, and updates the rating score of the film.
+// It returns the number of matched films.
+func SetFilmRating(idString string, source string, rating *RatingSource) (int64, error) {
+	id, err := primitive.ObjectIDFromHex(idString)
+	if err != nil {
+		return 0, err
+	}
+
+	// The update is a pipeline, so that the rating score is computed from the new ratings in a single atomic operation
+	pipeline := []bson.M{{"$unset": "ratings." + source}}
+	if rating != nil {
+		pipeline = []bson.M{{"$set": bson.M{"ratings." + source: rating}}}
+	}
+	pipeline = append(pipeline, ratingScoreStage)
+
+	// The rt_score mirrors the critics rating
+	if source == criticsRating && rating != nil {
+		pipeline = append(pipeline, bson.M{"$set": bson.M{"rt_score": formatCriticsScore(*rating)}})
+	} else if source == criticsRating {
+		pipeline = append(pipeline, bson.M{"$unset": "rt_score"})
+	}
+
+	result, err := filmColl.UpdateOne(context.TODO(), bson.M{"_id": id}, pipeline)
+	if err != nil {
+		return 0, err
+	}
+
+	catalogueChanged("film", idString)
+
+	return result.MatchedCount, nil
+}
+
+// BackfillRatings gives a critics rating source to the films created before the rating sources existed, from their rt_score
+func BackfillRatings() {
+	for _, film := range FindFilms(bson.M{"rt_score": bson.M{"$exists": true, "$ne": ""}, "ratings." + criticsRating: bson.M{"$exists": false}}, 0) {
+		rating, err := parseCriticsScore(film.Rating)
+		if err != nil {
+			fmt.Printf("The rt_score of the film %v can't be parsed\n", film.Id.Hex())
+			continue
+		}
+
+		if _, err := SetFilmRating(film.Id.Hex(), criticsRating, &rating); err != nil {
+			panic(err)
+		}
+	}
+}
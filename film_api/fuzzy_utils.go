@@ -0,0 +1,224 @@
+package film_api
+
+import (
+	"sort"
+	"strings"
+)
+
+// fuzzyMinScore is the confidence under which a text is not considered as matching the searched one
+const fuzzyMinScore = 0.6
+
+// partialMatchPenalty lowers the confidence of the matches which only cover some of the words of the matched text
+const partialMatchPenalty = 0.9
+
+// SearchResult is an item matching a searched text, with the confidence of the match between 0 and 1
+type SearchResult struct {
+	Suggestion
+	Score float64 `json:"score"`
+}
+
+// normalizeForMatching folds and transliterates a text, and returns its words
+func normalizeForMatching(text string) []string {
+	return strings.FieldsFunc(foldText(transliterate(text)), func(char rune) bool {
+		return !isWordChar(char)
+	})
+}
+
+// editDistance returns the Damerau-Levenshtein distance (with adjacent transpositions) between two strings
+func editDistance(a, b []rune) int {
+	if len(a) == 0 {
+		return len(b)
+	}
+	if len(b) == 0 {
+		return len(a)
+	}
+
+	// Only the last three rows of the matrix are needed
+	previousPrevious := make([]int, len(b)+1)
+	previous := make([]int, len(b)+1)
+	current := make([]int, len(b)+1)
+	for j := range previous {
+		previous[j] = j
+	}
+
+	for i := 1; i <= len(a); i++ {
+		current[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			current[j] = minInt(minInt(previous[j]+1, current[j-1]+1), previous[j-1]+cost)
+			if i > 1 && j > 1 && a[i-1] == b[j-2] && a[i-2] == b[j-1] {
+				current[j] = minInt(current[j], previousPrevious[j-2]+1)
+			}
+		}
+		previousPrevious, previous, current = previous, current, previousPrevious
+	}
+
+	return previous[len(b)]
+}
+
+func minInt(a, b int) int {
+	if a < b {
+		return a
+	}
+	return b
+}
+
+// stringSimilarity returns 1 minus the edit distance between the strings relative to the longest one
+func stringSimilarity(a, b string) float64 {
+	aChars, bChars := []rune(a), []rune(b)
+	longest := len(aChars)
+	if len(bChars) > longest {
+		longest = len(bChars)
+	}
+	if longest == 0 {
+		return 1
+	}
+
+	return 1 - float64(editDistance(aChars, bChars))/float64(longest)
+}
+
+// fuzzyScore returns the confidence, between 0 and 1, that the query designates the given text.
+// The words are compared regardless of their order, so that "Hisaishi Jo" matches "Joe Hisaishi",
+// and a query may only contain some of the words of the text, so that "Miyasaki" matches "Hayao Miyazaki".
+func fuzzyScore(query string, text string) float64 {
+	queryWords, textWords := normalizeForMatching(query), normalizeForMatching(text)
+	if len(queryWords) == 0 || len(textWords) == 0 {
+		return 0
+	}
+
+	score := stringSimilarity(strings.Join(queryWords, " "), strings.Join(textWords, " "))
+
+	if sortedScore := stringSimilarity(sortedWords(queryWords), sortedWords(textWords)); sortedScore > score {
+		score = sortedScore
+	}
+
+	// Each word of the query is matched with its closest word of the text
+	wordsScore := 0.0
+	for _, queryWord := range queryWords {
+		best := 0.0
+		for _, textWord := range textWords {
+			if similarity := stringSimilarity(queryWord, textWord); similarity > best {
+				best = similarity
+			}
+		}
+		wordsScore += best
+	}
+	wordsScore /= float64(len(queryWords))
+	if len(queryWords) < len(textWords) {
+		wordsScore *= partialMatchPenalty
+	}
+	if wordsScore > score {
+		score = wordsScore
+	}
+
+	return score
+}
+
+// fuzzySearch returns the items of the given types whose title or name is close to the query, the most confident first
+func fuzzySearch(query string, types map[string]bool, minScore float64, limit int) []SearchResult {
+	texts := catalogueAutocompleteIndex.load()
+
+	// best keeps, for each item, its best match
+	best := make(map[string]SearchResult)
+	for _, text := range texts {
+		if !types[text.Type] {
+			continue
+		}
+		score := fuzzyScore(query, text.Match)
+		if score < minScore {
+			continue
+		}
+		key := text.Type + text.Id
+		if previous, found := best[key]; !found || previous.Score < score {
+			best[key] = SearchResult{Suggestion: *text, Score: score}
+		}
+	}
+
+	return sortSearchResults(best, limit)
+}
+
+// search returns the items of the given types whose title or name contains the query, regardless of case and accents
+func search(query string, types map[string]bool, limit int) []SearchResult {
+	texts := catalogueAutocompleteIndex.load()
+	foldedQuery := strings.Join(normalizeForMatching(query), " ")
+
+	best := make(map[string]SearchResult)
+	for _, text := range texts {
+		if !types[text.Type] || len(foldedQuery) == 0 {
+			continue
+		}
+		if strings.Contains(strings.Join(normalizeForMatching(text.Match), " "), foldedQuery) {
+			best[text.Type+text.Id] = SearchResult{Suggestion: *text, Score: 1}
+		}
+	}
+
+	return sortSearchResults(best, limit)
+}
+
+func sortSearchResults(results map[string]SearchResult, limit int) []SearchResult {
+	sorted := make([]SearchResult, 0, len(results))
+	for _, result := range results {
+		sorted = append(sorted, result)
+	}
+	sort.Slice(sorted, func(i, j int) bool {
+		if sorted[i].Score != sorted[j].Score {
+			return sorted[i].Score > sorted[j].Score
+		}
+		return sorted[i].Match < sorted[j].Match
+	})
+
+	if limit > 0 && len(sorted) > limit {
+		sorted = sorted[:limit]
+	}
+
+	return sorted
+}
+
+// MatchPerson returns the existing actor or director whose name is the closest to the given one, with the confidence
+// of the match. It is used when people are added, to link existing people instead of creating duplicates.
+// It returns false if no name is close enough.
+func MatchPerson(name string) (SearchResult, bool) {
+	results := fuzzySearch(name, map[string]bool{"actor": true, "director": true}, fuzzyMinScore, 1)
+	if len(results) == 0 {
+		return SearchResult{}, false
+	}
+
+	return results[0], true
+}
+
+// FindNamesakes returns the existing people whose name is the given one, regardless of case, accents, script and word
+// order. Unlike MatchPerson, it doesn't match similar names, as different people often share a surname or have names
+// differing by a letter.
+func FindNamesakes(name string) []Suggestion {
+	normalizedName := sortedWords(normalizeForMatching(name))
+	if len(normalizedName) == 0 {
+		return []Suggestion{}
+	}
+
+	namesakes := []Suggestion{}
+	found := make(map[string]bool)
+	for _, text := range catalogueAutocompleteIndex.load() {
+		if (text.Type != "actor" && text.Type != "director") || found[text.Id] {
+			continue
+		}
+		if sortedWords(normalizeForMatching(text.Match)) == normalizedName {
+			namesakes = append(namesakes, *text)
+			found[text.Id] = true
+		}
+	}
+	sort.Slice(namesakes, func(i, j int) bool {
+		return namesakes[i].Id < namesakes[j].Id
+	})
+
+	return namesakes
+}
+
+// sortedWords joins the words in alphabetical order
+func sortedWords(words []string) string {
+	sorted := append([]string{}, words...)
+	sort.Strings(sorted)
+	return strings.Join(sorted, " ")
+}
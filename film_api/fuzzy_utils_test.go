@@ -0,0 +1,65 @@
+package film_api
+
+import "testing"
+
+func TestEditDistance(t *testing.T) {
+	tests := []struct {
+		a, b string
+		want int
+	}{
+		{"", "", 0},
+		{"", "abc", 3},
+		{"abc", "", 3},
+		{"miyazaki", "miyazaki", 0},
+		{"miyasaki", "miyazaki", 1},
+		{"hisaishi", "hisashi", 1},
+		{"kitten", "sitting", 3},
+		// An adjacent transposition costs a single edit
+		{"tarkovksy", "tarkovsky", 1},
+		{"서울", "서울의", 1},
+	}
+
+	for _, test := range tests {
+		if got := editDistance([]rune(test.a), []rune(test.b)); got != test.want {
+			t.Errorf("editDistance(%q, %q) = %v, want %v", test.a, test.b, got, test.want)
+		}
+	}
+}
+
+func TestFuzzyScore(t *testing.T) {
+	matches := []struct {
+		query, text string
+	}{
+		{"Miyasaki", "Hayao Miyazaki"},
+		{"Hisaishi Jo", "Joe Hisaishi"},
+		{"hayao miyazaki", "Hayao Miyazaki"},
+		{"Amelie", "Amélie"},
+		{"Tarkovsky", "Андрей Тарковский"},
+	}
+	for _, match := range matches {
+		if score := fuzzyScore(match.query, match.text); score < fuzzyMinScore {
+			t.Errorf("fuzzyScore(%q, %q) = %v, want at least %v", match.query, match.text, score, fuzzyMinScore)
+		}
+	}
+
+	misses := []struct {
+		query, text string
+	}{
+		{"Kurosawa", "Hayao Miyazaki"},
+		{"Totoro", "Spirited Away"},
+		{"", "Hayao Miyazaki"},
+		{"Hayao Miyazaki", ""},
+	}
+	for _, miss := range misses {
+		if score := fuzzyScore(miss.query, miss.text); score >= fuzzyMinScore {
+			t.Errorf("fuzzyScore(%q, %q) = %v, want less than %v", miss.query, miss.text, score, fuzzyMinScore)
+		}
+	}
+
+	if score := fuzzyScore("Hayao Miyazaki", "HAYAO MIYAZAKI"); score != 1 {
+		t.Errorf("fuzzyScore of the same name = %v, want 1", score)
+	}
+	if exact, partial := fuzzyScore("Hayao Miyazaki", "Hayao Miyazaki"), fuzzyScore("Miyazaki", "Hayao Miyazaki"); partial >= exact {
+		t.Errorf("fuzzyScore of a partial name = %v, want less than the full name %v", partial, exact)
+	}
+}
@@ -0,0 +1,198 @@
+package film_api
+
+import (
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"net/http"
+	"strconv"
+)
+
+type Genre struct {
+	Id    primitive.ObjectID `json:"id" bson:"_id,omitempty"`
+	Slug  string             `json:"slug,omitempty" bson:"slug,omitempty"`
+	Name  string             `json:"name" bson:"name,omitempty"`
+	Films []string           `json:"films" bson:"films"` // Films is the slice of the ids of the films of the genre
+}
+
+type GenreCount struct {
+	GenreId string `json:"genre" bson:"_id"`
+	Name    string `json:"name" bson:"name"`
+	Count   int    `json:"count" bson:"count"`
+}
+
+func InitGenreApiRoutes(apiRoutes *gin.RouterGroup, client *mongo.Client) {
+	InitGenreCollection(client)
+	InitSlugHistoryCollection(client)
+	InitAuditCollection(client)
+
+	genreRoutes := apiRoutes.Group("/genres")
+	genreRoutes.Use(ResolveSlug("genre"))
+	genreRoutes.Use(Audit("genre", genreColl))
+	genreRoutes.GET("/", GetGenres)
+	genreRoutes.GET("/counts", GetGenreCounts)
+	genreRoutes.GET("/:id", GetGenreById)
+	genreRoutes.POST("/", RequireScope(writeFilmsScope), PostGenre)
+	genreRoutes.PATCH("/:id", RequireScope(writeFilmsScope), UpdateGenre)
+	genreRoutes.DELETE("/:id", RequireScope(writeFilmsScope), DeleteGenre)
+}
+
+func GetGenres(c *gin.Context) {
+	limit := 0
+	if l := c.Query("limit"); len(l) > 0 {
+		limit, _ = strconv.Atoi(l)
+	}
+
+	genres := FindGenres(bson.M{}, limit)
+	c.IndentedJSON(http.StatusOK, genres)
+}
+
+// GetGenreCounts returns the number of films of each genre, among the films matching the filters of GetFilms
+func GetGenreCounts(c *gin.Context) {
+	filter, ok := filmsFilter(c)
+	if !ok {
+		return
+	}
+
+	c.IndentedJSON(http.StatusOK, CountFilmsPerGenre(filter))
+}
+
+func GetGenreById(c *gin.Context) {
+	id, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		c.IndentedJSON(http.StatusBadRequest, gin.H{"message": "Id is invalid"})
+		return
+	}
+
+	genre := FindGenre(bson.M{"_id": id})
+	if len(genre.Name) > 0 {
+		c.IndentedJSON(http.StatusOK, genre)
+	} else {
+		c.IndentedJSON(http.StatusNotFound, gin.H{"message": "Genre not found"})
+	}
+}
+
+func PostGenre(c *gin.Context) {
+	var newGenre Genre
+	newGenre.Films = []string{}
+
+	if err := c.BindJSON(&newGenre); err != nil {
+		return
+	}
+
+	if len(newGenre.Name) == 0 {
+		c.IndentedJSON(http.StatusBadRequest, gin.H{"message": "The name is required"})
+		return
+	}
+
+	if valid, err := AreFilmsIdsValid(newGenre.Films); !valid {
+		c.IndentedJSON(http.StatusBadRequest, gin.H{"message": err})
+		return
+	}
+
+	newGenre, err := AddGenre(newGenre)
+	if err != nil {
+		c.IndentedJSON(http.StatusBadRequest, gin.H{"message": err.Error()})
+		return
+	}
+
+	// Add the genre id to its films
+	for _, film := range newGenre.Films {
+		filmInter := film
+		go func() {
+			_, err := AddGenresToFilm(filmInter, []string{newGenre.Id.Hex()})
+			if err != nil {
+				panic(err)
+			}
+		}()
+	}
+
+	c.IndentedJSON(http.StatusCreated, newGenre)
+}
+
+func UpdateGenre(c *gin.Context) {
+	var updateData Genre
+	idString := c.Param("id")
+
+	id, err := primitive.ObjectIDFromHex(idString)
+	if err != nil {
+		c.IndentedJSON(http.StatusBadRequest, gin.H{"message": err.Error()})
+		return
+	}
+
+	oldGenre := FindGenre(bson.M{"_id": id})
+
+	if err := c.BindJSON(&updateData); err != nil {
+		c.IndentedJSON(http.StatusBadRequest, gin.H{"message": err.Error()})
+		return
+	}
+
+	if valid, err := AreFilmsIdsValid(updateData.Films); !valid {
+		c.IndentedJSON(http.StatusBadRequest, gin.H{"message": err})
+		return
+	}
+
+	updateData.Slug = ""
+	if len(updateData.Name) > 0 && updateData.Name != oldGenre.Name {
+		if updateData.Slug, err = renameSlug("genre", id, oldGenre.Slug, slugify(updateData.Name)); err != nil {
+			c.IndentedJSON(http.StatusInternalServerError, gin.H{"message": err.Error()})
+			return
+		}
+	}
+	if updateData.Films == nil {
+		updateData.Films = []string{}
+	}
+
+	result := UpdateGenreById(idString, updateData)
+
+	removedFilms := difference(oldGenre.Films, updateData.Films)
+	for _, film := range removedFilms {
+		film := film
+		go func() {
+			_, err := RemoveGenresFromFilm(film, []string{idString})
+			if err != nil {
+				panic(err)
+			}
+		}()
+	}
+
+	newFilms := difference(updateData.Films, oldGenre.Films)
+	for _, film := range newFilms {
+		film := film
+		go func() {
+			_, err := AddGenresToFilm(film, []string{idString})
+			if err != nil {
+				panic(err)
+			}
+		}()
+	}
+
+	c.IndentedJSON(http.StatusNoContent, result)
+}
+
+func DeleteGenre(c *gin.Context) {
+	id, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		c.IndentedJSON(http.StatusBadRequest, gin.H{"message": err.Error()})
+		return
+	}
+
+	oldGenre := FindGenre(bson.M{"_id": id})
+
+	result := DeleteItemById(genreColl, id.Hex())
+
+	if result == 0 {
+		c.IndentedJSON(http.StatusNotModified, gin.H{"message": "No genre with the specified id"})
+		return
+	}
+
+	for _, film := range oldGenre.Films {
+		_, err := RemoveGenresFromFilm(film, []string{id.Hex()})
+		if err != nil {
+			return
+		}
+	}
+
+	c.IndentedJSON(http.StatusNoContent, result)
+}
@@ -0,0 +1,143 @@
+package film_api
+
+import (
+	"context"
+	"filmflix/db_connection"
+	"fmt"
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+var genreColl *mongo.Collection
+
+func InitGenreCollection(client *mongo.Client) {
+	genreColl = db_connection.GetCollection(client, "films", "genres")
+	createSlugIndex(genreColl)
+}
+
+func FindGenres(filter bson.M, maxCount int) []Genre {
+	var results []Genre
+	limit := int64(maxCount)
+	cursor, err := genreColl.Find(context.TODO(), filter, &options.FindOptions{Limit: &limit, Sort: bson.M{"name": 1}})
+	if err != nil {
+		panic(err)
+	}
+
+	if err = cursor.All(context.TODO(), &results); err != nil {
+		panic(err)
+	}
+
+	return results
+}
+
+func FindGenre(filter bson.M) Genre {
+	var genre Genre
+	err := genreColl.FindOne(context.TODO(), filter).Decode(&genre)
+
+	if err == mongo.ErrNoDocuments {
+		fmt.Printf("No document was found\n")
+		return genre
+	}
+	if err != nil {
+		panic(err)
+	}
+
+	return genre
+}
+
+func AddGenre(genre Genre) (Genre, error) {
+	genre.Id = primitive.NewObjectID()
+	genre.Slug = UniqueSlug("genre", slugify(genre.Name), genre.Id)
+
+	_, err := genreColl.InsertOne(context.TODO(), genre)
+	// Another genre may have taken the slug since it was chosen
+	if mongo.IsDuplicateKeyError(err) {
+		genre.Slug = UniqueSlug("genre", slugify(genre.Name), genre.Id)
+		_, err = genreColl.InsertOne(context.TODO(), genre)
+	}
+	if err != nil {
+		return Genre{}, err
+	}
+
+	return genre, nil
+}
+
+func UpdateGenreById(idString string, data interface{}) int64 {
+	id, _ := primitive.ObjectIDFromHex(idString)
+
+	result, err := genreColl.UpdateOne(context.TODO(), bson.M{"_id": id}, bson.M{"$set": data})
+	if err != nil {
+		panic(err)
+	}
+
+	return result.ModifiedCount
+}
+
+func AddFilmsToGenre(idString string, films []string) (int64, error) {
+	id, err := primitive.ObjectIDFromHex(idString)
+	if err != nil {
+		return 0, err
+	}
+
+	result, err := genreColl.UpdateOne(context.TODO(), bson.M{"_id": id}, bson.M{"$addToSet": bson.M{"films": bson.M{"$each": films}}})
+	if err != nil {
+		return 0, err
+	}
+
+	return result.ModifiedCount, nil
+}
+
+func RemoveFilmsFromGenre(idString string, films []string) (int64, error) {
+	id, err := primitive.ObjectIDFromHex(idString)
+	if err != nil {
+		return 0, err
+	}
+
+	result, err := genreColl.UpdateOne(context.TODO(), bson.M{"_id": id}, bson.M{"$pull": bson.M{"films": bson.M{"$in": films}}})
+	if err != nil {
+		return 0, err
+	}
+
+	return result.ModifiedCount, nil
+}
+
+// CountFilmsPerGenre returns the number of films of each genre matching the given films filter, the largest genres first
+func CountFilmsPerGenre(filmsFilter bson.M) []GenreCount {
+	results := []GenreCount{}
+	pipeline := []bson.M{
+		{"$match": filmsFilter},
+		{"$unwind": "$genres"},
+		{"$group": bson.M{"_id": "$genres", "count": bson.M{"$sum": 1}}},
+		{"$sort": bson.D{{Key: "count", Value: -1}, {Key: "_id", Value: 1}}},
+	}
+	aggregate(filmColl, append(pipeline, lookupNameStages(genreColl.Name())...), &results)
+
+	return results
+}
+
+func AreGenresIdsValid(ids []string) (bool, gin.H) {
+	tempIds := make(map[string]struct{})
+	for i, id := range ids {
+		tempIds[id] = struct{}{}
+		if !primitive.IsValidObjectID(id) {
+			return false, gin.H{"message": fmt.Sprintf("Id of genre no. %v is invalid", i)}
+		}
+	}
+
+	genresIds := make([]primitive.ObjectID, len(tempIds))
+	i := 0
+	for k := range tempIds {
+		genresIds[i], _ = primitive.ObjectIDFromHex(k)
+		i++
+	}
+
+	result := FindGenres(bson.M{"_id": bson.M{"$in": genresIds}}, len(genresIds))
+
+	if len(result) != len(genresIds) {
+		return false, gin.H{"message": "At least one genre id does not exists"}
+	}
+	return true, nil
+}
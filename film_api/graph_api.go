@@ -0,0 +1,104 @@
+package film_api
+
+import (
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"net/http"
+	"strconv"
+)
+
+type CoStarResult struct {
+	Actor       Actor    `json:"actor"`
+	SharedFilms []string `json:"shared_films"` // SharedFilms is the slice of the ids of the films both actors played in
+	Count       int      `json:"count"`
+}
+
+// PathStep is a node of a chain linking two people, either a person (actor or director) or a film
+type PathStep struct {
+	Type string `json:"type"` // Type is either "person" or "film"
+	Id   string `json:"id"`
+	Name string `json:"name"`
+}
+
+func InitGraphApiRoutes(apiRoutes *gin.RouterGroup) {
+	graphRoutes := apiRoutes.Group("/graph")
+	graphRoutes.GET("/path", GetGraphPath)
+}
+
+// GetActorCoStars returns the actors who shared at least one film with the given actor, ranked by number of shared films
+func GetActorCoStars(c *gin.Context) {
+	id, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		c.IndentedJSON(http.StatusBadRequest, gin.H{"message": "Id is invalid"})
+		return
+	}
+
+	limit := 20
+	if l := c.Query("limit"); len(l) > 0 {
+		limit, _ = strconv.Atoi(l)
+	}
+
+	coStars := catalogueGraph.load().coStars(id.Hex())
+	if limit > 0 && len(coStars) > limit {
+		coStars = coStars[:limit]
+	}
+
+	actorsIds := make([]primitive.ObjectID, len(coStars))
+	for i, coStar := range coStars {
+		actorsIds[i], _ = primitive.ObjectIDFromHex(coStar.ActorId)
+	}
+	actors := make(map[string]Actor, len(coStars))
+	for _, actor := range FindActors(bson.M{"_id": bson.M{"$in": actorsIds}}, len(actorsIds)) {
+		actors[actor.Id.Hex()] = actor
+	}
+
+	results := make([]CoStarResult, 0, len(coStars))
+	for _, coStar := range coStars {
+		actor, found := actors[coStar.ActorId]
+		if !found {
+			continue
+		}
+		results = append(results, CoStarResult{Actor: actor, SharedFilms: coStar.SharedFilms, Count: len(coStar.SharedFilms)})
+	}
+
+	c.IndentedJSON(http.StatusOK, results)
+}
+
+// GetGraphPath returns the shortest chain of films connecting two people (actors or directors)
+func GetGraphPath(c *gin.Context) {
+	from, to := c.Query("from"), c.Query("to")
+	if !primitive.IsValidObjectID(from) || !primitive.IsValidObjectID(to) {
+		c.IndentedJSON(http.StatusBadRequest, gin.H{"message": "from and to must be valid person ids"})
+		return
+	}
+
+	path := catalogueGraph.load().shortestPath(from, to)
+	if path == nil {
+		c.IndentedJSON(http.StatusNotFound, gin.H{"message": "No path links these two people"})
+		return
+	}
+
+	steps := make([]PathStep, len(path))
+	for i, nodeId := range path {
+		// The path alternates people and films, starting with a person
+		if i%2 == 0 {
+			steps[i] = PathStep{Type: "person", Id: nodeId, Name: findPersonName(nodeId)}
+		} else {
+			id, _ := primitive.ObjectIDFromHex(nodeId)
+			steps[i] = PathStep{Type: "film", Id: nodeId, Name: FindFilm(bson.M{"_id": id}).Title}
+		}
+	}
+
+	c.IndentedJSON(http.StatusOK, gin.H{"degrees": len(path) / 2, "path": steps})
+}
+
+// findPersonName returns the name of the actor or director with the given id
+func findPersonName(idString string) string {
+	id, _ := primitive.ObjectIDFromHex(idString)
+
+	if actor := FindActor(bson.M{"_id": id}); len(actor.Name) > 0 {
+		return actor.Name
+	}
+	return FindDirector(bson.M{"_id": id}).Name
+}
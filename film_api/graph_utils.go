@@ -0,0 +1,160 @@
+package film_api
+
+import (
+	"go.mongodb.org/mongo-driver/bson"
+	"sort"
+	"sync"
+)
+
+// graphData is the bipartite graph linking the people (actors and directors) to the films they worked on
+type graphData struct {
+	personFilms   map[string][]string // personFilms maps a person id to the ids of the films he worked on
+	filmActors    map[string][]string // filmActors maps a film id to the ids of the actors who played in it
+	filmPeopleIds map[string][]string // filmPeopleIds maps a film id to the ids of all the people who worked on it
+}
+
+// filmGraph holds the graph built in memory from the films collection, which is rebuilt lazily after each write
+type filmGraph struct {
+	mutex sync.Mutex
+	dirty bool
+	data  *graphData
+}
+
+var catalogueGraph = &filmGraph{dirty: true}
+
+func (g *filmGraph) invalidate() {
+	g.mutex.Lock()
+	g.dirty = true
+	g.mutex.Unlock()
+}
+
+// load returns the graph, rebuilding it first if the films collection changed since the last build.
+// The returned graph is never modified afterwards, so it can be read without holding the lock.
+func (g *filmGraph) load() *graphData {
+	g.mutex.Lock()
+	defer g.mutex.Unlock()
+
+	if !g.dirty {
+		return g.data
+	}
+
+	data := &graphData{
+		personFilms:   make(map[string][]string),
+		filmActors:    make(map[string][]string),
+		filmPeopleIds: make(map[string][]string),
+	}
+
+	for _, film := range FindFilms(bson.M{}, 0) {
+		filmId := film.Id.Hex()
+		seen := make(map[string]struct{})
+		addPerson := func(personId string) {
+			if _, found := seen[personId]; found || len(personId) == 0 {
+				return
+			}
+			seen[personId] = struct{}{}
+			data.filmPeopleIds[filmId] = append(data.filmPeopleIds[filmId], personId)
+			data.personFilms[personId] = append(data.personFilms[personId], filmId)
+		}
+
+		for _, director := range film.Directors {
+			addPerson(director)
+		}
+
+		actorsSeen := make(map[string]struct{})
+		for _, role := range film.Roles {
+			if _, found := actorsSeen[role.ActorId]; found || len(role.ActorId) == 0 {
+				continue
+			}
+			actorsSeen[role.ActorId] = struct{}{}
+			data.filmActors[filmId] = append(data.filmActors[filmId], role.ActorId)
+			addPerson(role.ActorId)
+		}
+	}
+
+	g.data = data
+	g.dirty = false
+
+	return data
+}
+
+type coStar struct {
+	ActorId     string
+	SharedFilms []string
+}
+
+// coStars returns the actors who played in at least one film with the given actor, the ones who shared the most films first
+func (g *graphData) coStars(actorId string) []coStar {
+	shared := make(map[string][]string)
+	var order []string
+
+	for _, film := range g.personFilms[actorId] {
+		if !containsString(g.filmActors[film], actorId) {
+			continue
+		}
+		for _, other := range g.filmActors[film] {
+			if other == actorId {
+				continue
+			}
+			if _, found := shared[other]; !found {
+				order = append(order, other)
+			}
+			shared[other] = append(shared[other], film)
+		}
+	}
+
+	results := make([]coStar, len(order))
+	for i, other := range order {
+		results[i] = coStar{ActorId: other, SharedFilms: shared[other]}
+	}
+	sort.SliceStable(results, func(i, j int) bool {
+		return len(results[i].SharedFilms) > len(results[j].SharedFilms)
+	})
+
+	return results
+}
+
+// shortestPath finds, with a breadth-first search, the shortest chain of films linking two people.
+// The returned slice alternates people and films ids, starting with from and ending with to,
+// and is nil if the two people are not connected.
+func (g *graphData) shortestPath(from, to string) []string {
+	if _, found := g.personFilms[from]; !found {
+		return nil
+	}
+	if from == to {
+		return []string{from}
+	}
+
+	// previous maps a visited node (person or film) to the node it was reached from
+	previous := map[string]string{from: ""}
+	queue := []string{from}
+
+	for len(queue) > 0 {
+		person := queue[0]
+		queue = queue[1:]
+
+		for _, film := range g.personFilms[person] {
+			if _, visited := previous[film]; visited {
+				continue
+			}
+			previous[film] = person
+
+			for _, other := range g.filmPeopleIds[film] {
+				if _, visited := previous[other]; visited {
+					continue
+				}
+				previous[other] = film
+
+				if other == to {
+					var path []string
+					for node := to; node != ""; node = previous[node] {
+						path = append([]string{node}, path...)
+					}
+					return path
+				}
+				queue = append(queue, other)
+			}
+		}
+	}
+
+	return nil
+}
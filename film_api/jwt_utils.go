@@ -0,0 +1,109 @@
+package film_api
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"os"
+	"strings"
+	"time"
+)
+
+// accessTokenDuration is the lifetime of the access tokens, after which a refresh token must be used
+const accessTokenDuration = 15 * time.Minute
+
+// jwtHeader is the encoded header of the tokens, which are all signed with HMAC-SHA256
+var jwtHeader = base64.RawURLEncoding.EncodeToString([]byte(`{"alg":"HS256","typ":"JWT"}`))
+
+var errInvalidToken = errors.New("the token is invalid")
+
+// TokenClaims are the claims of the access tokens given to the users
+type TokenClaims struct {
+	Subject   string `json:"sub"` // Subject is the id of the user
+	Name      string `json:"name"`
+	Role      string `json:"role"`
+	IssuedAt  int64  `json:"iat"`
+	ExpiresAt int64  `json:"exp"`
+}
+
+// jwtSecret returns the key signing the tokens, taken from the JWT_SECRET environment variable
+func jwtSecret() ([]byte, error) {
+	secret := os.Getenv("JWT_SECRET")
+	if len(secret) == 0 {
+		return nil, errors.New("JWT_SECRET is not set")
+	}
+	return []byte(secret), nil
+}
+
+// newAccessToken returns a signed access token for the user
+func newAccessToken(user User) (string, error) {
+	now := time.Now()
+	claims, err := json.Marshal(TokenClaims{
+		Subject:   user.Id.Hex(),
+		Name:      user.Name,
+		Role:      user.role(),
+		IssuedAt:  now.Unix(),
+		ExpiresAt: now.Add(accessTokenDuration).Unix(),
+	})
+	if err != nil {
+		return "", err
+	}
+
+	payload := jwtHeader + "." + base64.RawURLEncoding.EncodeToString(claims)
+	signature, err := signJWT(payload)
+	if err != nil {
+		return "", err
+	}
+
+	return payload + "." + signature, nil
+}
+
+// parseAccessToken checks the signature and the expiry of an access token and returns its claims
+func parseAccessToken(token string) (TokenClaims, error) {
+	var claims TokenClaims
+
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 || parts[0] != jwtHeader {
+		return claims, errInvalidToken
+	}
+
+	signature, err := signJWT(parts[0] + "." + parts[1])
+	if err != nil {
+		return claims, err
+	}
+	if !hmac.Equal([]byte(signature), []byte(parts[2])) {
+		return claims, errInvalidToken
+	}
+
+	payload, err := base64.RawURLEncoding.DecodeString(parts[1])
+	if err != nil {
+		return claims, errInvalidToken
+	}
+	if err = json.Unmarshal(payload, &claims); err != nil {
+		return claims, errInvalidToken
+	}
+
+	if time.Now().Unix() >= claims.ExpiresAt {
+		return claims, errors.New("the token has expired")
+	}
+
+	return claims, nil
+}
+
+func signJWT(payload string) (string, error) {
+	secret, err := jwtSecret()
+	if err != nil {
+		return "", err
+	}
+
+	mac := hmac.New(sha256.New, secret)
+	mac.Write([]byte(payload))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil)), nil
+}
+
+// isJWT tells whether a bearer token is an access token rather than an API key
+func isJWT(token string) bool {
+	return strings.Count(token, ".") == 2
+}
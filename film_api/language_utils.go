@@ -0,0 +1,89 @@
+package film_api
+
+import (
+	"github.com/gin-gonic/gin"
+	"golang.org/x/text/language"
+	"os"
+	"strings"
+)
+
+// defaultLanguage is the last language of the fallback chains when the DEFAULT_LANGUAGE env var is not set
+const defaultLanguage = "en"
+
+// normalizeLanguage returns the canonical lowercase form of a language tag, like "fr" or "fr-ca"
+func normalizeLanguage(tag string) (string, error) {
+	parsed, err := language.Parse(tag)
+	if err != nil {
+		return "", err
+	}
+
+	return strings.ToLower(parsed.String()), nil
+}
+
+// requestedLanguages returns the fallback chain of the languages wanted by the client, the preferred one first.
+// The languages are taken from the "lang" query param, which is a comma separated list, or from the Accept-Language header.
+// Each regional language is followed by its base language ("fr-ca" then "fr") and the chain ends with the default language.
+func requestedLanguages(c *gin.Context) []string {
+	var tags []language.Tag
+	if lang := c.Query("lang"); len(lang) > 0 {
+		for _, l := range strings.Split(lang, ",") {
+			if tag, err := language.Parse(strings.TrimSpace(l)); err == nil {
+				tags = append(tags, tag)
+			}
+		}
+	} else if header := c.GetHeader("Accept-Language"); len(header) > 0 {
+		// The tags are sorted by decreasing quality
+		tags, _, _ = language.ParseAcceptLanguage(header)
+	}
+
+	fallback := os.Getenv("DEFAULT_LANGUAGE")
+	if len(fallback) == 0 {
+		fallback = defaultLanguage
+	}
+
+	var chain []string
+	add := func(l string) {
+		if !containsString(chain, l) {
+			chain = append(chain, l)
+		}
+	}
+	for _, tag := range tags {
+		add(strings.ToLower(tag.String()))
+		if base, confidence := tag.Base(); confidence != language.No {
+			add(base.String())
+		}
+	}
+	add(fallback)
+
+	return chain
+}
+
+// localizeFilm replaces the title and the description of the film by their translation in the first language
+// of the chain having one, each field falling back independently
+func localizeFilm(film *Film, languages []string) {
+	titleFound, descriptionFound := false, false
+
+	for _, l := range languages {
+		localization, found := film.Localizations[l]
+		if !found {
+			continue
+		}
+		if !titleFound && len(localization.Title) > 0 {
+			film.Title = localization.Title
+			titleFound = true
+		}
+		if !descriptionFound && len(localization.Description) > 0 {
+			film.Description = localization.Description
+			descriptionFound = true
+		}
+	}
+}
+
+// localizeFilms localizes the films for the client and sets the Content-Language header to its preferred language
+func localizeFilms(c *gin.Context, films []Film) {
+	languages := requestedLanguages(c)
+	for i := range films {
+		localizeFilm(&films[i], languages)
+	}
+	c.Header("Content-Language", languages[0])
+}
@@ -0,0 +1,137 @@
+package film_api
+
+import (
+	"encoding/csv"
+	"fmt"
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"io"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// letterboxdDateLayout is the layout of the dates in the Letterboxd CSV exports
+const letterboxdDateLayout = "2006-01-02"
+
+// importMatchMinScore is the confidence from which a film of an imported file is considered to be a film of the catalogue
+const importMatchMinScore = 0.85
+
+var (
+	letterboxdWatchlistHeader = []string{"Date", "Name", "Year", "Letterboxd URI"}
+	letterboxdDiaryHeader     = []string{"Date", "Name", "Year", "Letterboxd URI", "Rating", "Rewatch", "Tags", "Watched Date"}
+)
+
+// ImportReport tells which rows of an imported file were imported and which films could not be found
+type ImportReport struct {
+	Imported int      `json:"imported"`
+	Skipped  []string `json:"skipped"` // Skipped are the names of the films which are not in the catalogue or already imported
+}
+
+// letterboxdRow is a row of a Letterboxd CSV file, whose values are accessed by column name
+type letterboxdRow map[string]string
+
+// readLetterboxdCSV reads the rows of the CSV file sent as "file" field of a multipart form or as request body
+func readLetterboxdCSV(c *gin.Context) ([]letterboxdRow, error) {
+	var reader io.Reader = c.Request.Body
+	if strings.HasPrefix(c.ContentType(), "multipart/form-data") {
+		header, err := c.FormFile("file")
+		if err != nil {
+			return nil, err
+		}
+		file, err := header.Open()
+		if err != nil {
+			return nil, err
+		}
+		defer file.Close()
+		reader = file
+	}
+
+	records, err := csv.NewReader(reader).ReadAll()
+	if err != nil {
+		return nil, err
+	}
+	if len(records) == 0 {
+		return nil, fmt.Errorf("the file is empty")
+	}
+
+	header := records[0]
+	rows := make([]letterboxdRow, 0, len(records)-1)
+	for _, record := range records[1:] {
+		row := make(letterboxdRow, len(header))
+		for i, column := range header {
+			if i < len(record) {
+				row[strings.TrimSpace(column)] = strings.TrimSpace(record[i])
+			}
+		}
+		rows = append(rows, row)
+	}
+
+	return rows, nil
+}
+
+// writeLetterboxdCSV sends the records as a CSV file to download
+func writeLetterboxdCSV(c *gin.Context, fileName string, header []string, records [][]string) {
+	c.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="%v"`, fileName))
+	c.Header("Content-Type", "text/csv; charset=utf-8")
+
+	writer := csv.NewWriter(c.Writer)
+	_ = writer.Write(header)
+	_ = writer.WriteAll(records)
+}
+
+// matchImportedFilm returns the film of the catalogue matching the name and the year of an imported row
+func matchImportedFilm(row letterboxdRow) (Film, bool) {
+	year, _ := strconv.Atoi(row["Year"])
+
+	for _, result := range fuzzySearch(row["Name"], map[string]bool{"film": true}, importMatchMinScore, 10) {
+		id, _ := primitive.ObjectIDFromHex(result.Id)
+		film := FindFilm(bson.M{"_id": id})
+		if year == 0 || film.Release.Year() == year {
+			return film, true
+		}
+	}
+
+	return Film{}, false
+}
+
+// parseLetterboxdRating converts a Letterboxd rating, from 0.5 to 5 stars, to a rating from 1 to 10, or 0 if there is none
+func parseLetterboxdRating(value string) int {
+	stars, err := strconv.ParseFloat(value, 64)
+	if err != nil || stars <= 0 {
+		return 0
+	}
+
+	return int(math.Max(minRating, math.Min(maxRating, math.Round(stars*2))))
+}
+
+func formatLetterboxdRating(rating int) string {
+	if rating == 0 {
+		return ""
+	}
+	return strconv.FormatFloat(float64(rating)/2, 'f', -1, 64)
+}
+
+func parseLetterboxdDate(value string) (time.Time, bool) {
+	date, err := time.Parse(letterboxdDateLayout, value)
+	return date, err == nil
+}
+
+// filmsByIds returns the films with the given ids, mapped by id
+func filmsByIds(ids []string) map[string]Film {
+	filmsIds := make([]primitive.ObjectID, 0, len(ids))
+	for _, id := range ids {
+		if filmId, err := primitive.ObjectIDFromHex(id); err == nil {
+			filmsIds = append(filmsIds, filmId)
+		}
+	}
+
+	films := make(map[string]Film, len(filmsIds))
+	for _, film := range FindFilms(bson.M{"_id": bson.M{"$in": filmsIds}}, len(filmsIds)) {
+		films[film.Id.Hex()] = film
+	}
+
+	return films
+}
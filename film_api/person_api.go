@@ -0,0 +1,276 @@
+package film_api
+
+import (
+	"fmt"
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// Person is someone credited on films. The facets tell whether the person acts, directs or is a crew member,
+// the actors and directors routes being views over the people having the matching facet.
+type Person struct {
+	Id            primitive.ObjectID `json:"id" bson:"_id,omitempty"`
+	Slug          string             `json:"slug,omitempty" bson:"slug,omitempty"`
+	Name          string             `json:"name" bson:"name,omitempty"`
+	Facets        []string           `json:"facets" bson:"facets,omitempty"`
+	ActedFilms    []string           `json:"acted_films" bson:"acted_films,omitempty"`
+	DirectedFilms []string           `json:"directed_films" bson:"directed_films,omitempty"`
+	Films         []string           `json:"films" bson:"films,omitempty"` // Films is the slice of the films the person is credited on as a crew member
+
+	// DeletedAt is set while the person is in the trash, with the roles and the credits removed from the films
+	DeletedAt      *time.Time      `json:"deleted_at,omitempty" bson:"deleted_at,omitempty"`
+	DeletedRoles   []TrashedRole   `json:"-" bson:"deleted_roles,omitempty"`
+	DeletedCredits []TrashedCredit `json:"-" bson:"deleted_credits,omitempty"`
+}
+
+const (
+	actorFacet    = "actor"
+	directorFacet = "director"
+	crewFacet     = "crew"
+)
+
+var personFacets = []string{actorFacet, directorFacet, crewFacet}
+
+func InitPersonApiRoutes(apiRoutes *gin.RouterGroup, client *mongo.Client) {
+	InitPersonCollection(client)
+	InitRedirectCollection(client)
+	InitSlugHistoryCollection(client)
+	InitAuditCollection(client)
+	InitRevisionCollection(client)
+
+	personRoutes := apiRoutes.Group("/people")
+	personRoutes.Use(ResolveSlug("person"))
+	personRoutes.Use(Audit("person", personColl))
+	personRoutes.GET("/", GetPeople)
+	personRoutes.POST("/", RequireScope(writePeopleScope), PostPerson)
+	personRoutes.GET("/:id", GetPersonById)
+	personRoutes.PATCH("/:id", RequireScope(writePeopleScope), UpdatePerson)
+	personRoutes.DELETE("/:id", RequireScope(writePeopleScope), DeletePerson)
+	personRoutes.POST("/:id/merge", RequireScope(writePeopleScope), MergePerson)
+	initRevisionRoutes(personRoutes, "person", writePeopleScope)
+}
+
+// GetPeople returns the people, only the ones having the facet given with ?facet=<facet> if any
+func GetPeople(c *gin.Context) {
+	limit := 20
+	if l := c.Query("limit"); len(l) > 0 {
+		limit, _ = strconv.Atoi(l)
+	}
+
+	filter := bson.M{}
+	if facet := c.Query("facet"); len(facet) > 0 {
+		if !containsString(personFacets, facet) {
+			c.IndentedJSON(http.StatusBadRequest, gin.H{"message": "Facet is invalid"})
+			return
+		}
+		filter = withFacet(filter, facet)
+	}
+
+	c.IndentedJSON(http.StatusOK, FindPeople(filter, limit))
+}
+
+func GetPersonById(c *gin.Context) {
+	id, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		c.IndentedJSON(http.StatusBadRequest, gin.H{"message": "Id is invalid"})
+		return
+	}
+
+	person := FindPersonById(id)
+	if len(person.Name) > 0 {
+		c.IndentedJSON(http.StatusOK, person)
+	} else {
+		c.IndentedJSON(http.StatusNotFound, gin.H{"message": "Person not found"})
+	}
+}
+
+// PostPerson adds a person without films, who is then credited on films with PUT /api/films/<id>/credits
+func PostPerson(c *gin.Context) {
+	var newPerson Person
+	if err := c.BindJSON(&newPerson); err != nil {
+		return
+	}
+
+	if len(newPerson.Name) == 0 {
+		c.IndentedJSON(http.StatusBadRequest, gin.H{"message": "The name is required"})
+		return
+	}
+	if len(newPerson.Facets) == 0 {
+		newPerson.Facets = []string{crewFacet}
+	}
+	if valid, msg := areFacetsValid(newPerson.Facets); !valid {
+		c.IndentedJSON(http.StatusBadRequest, msg)
+		return
+	}
+
+	if isDuplicatePerson(c, newPerson.Name) {
+		return
+	}
+
+	newPerson, err := AddPerson(newPerson)
+	if err != nil {
+		c.IndentedJSON(http.StatusInternalServerError, gin.H{"message": err.Error()})
+		return
+	}
+
+	c.IndentedJSON(http.StatusCreated, newPerson)
+}
+
+// UpdatePerson updates the name and the facets of a person, the films are updated through the credits of the films.
+// A facet can only be removed from a person without films for this facet.
+func UpdatePerson(c *gin.Context) {
+	id, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		c.IndentedJSON(http.StatusBadRequest, gin.H{"message": "Id is invalid"})
+		return
+	}
+
+	var updateData Person
+	if err := c.BindJSON(&updateData); err != nil {
+		c.IndentedJSON(http.StatusBadRequest, gin.H{"message": err.Error()})
+		return
+	}
+
+	oldData := FindPerson(bson.M{"_id": id})
+	if oldData.Id.IsZero() {
+		c.IndentedJSON(http.StatusNotFound, gin.H{"message": "Person not found"})
+		return
+	}
+
+	data := bson.M{}
+	if len(updateData.Facets) > 0 {
+		if valid, msg := areFacetsValid(updateData.Facets); !valid {
+			c.IndentedJSON(http.StatusBadRequest, msg)
+			return
+		}
+		for _, facet := range difference(oldData.Facets, updateData.Facets) {
+			if len(personFacetFilms(oldData, facet)) > 0 {
+				c.IndentedJSON(http.StatusBadRequest, gin.H{"message": fmt.Sprintf("The person still has films as %v", facet)})
+				return
+			}
+		}
+		data["facets"] = updateData.Facets
+	}
+
+	if len(updateData.Name) > 0 && updateData.Name != oldData.Name {
+		slug, err := renameSlug("person", id, oldData.Slug, slugify(updateData.Name))
+		if err != nil {
+			c.IndentedJSON(http.StatusInternalServerError, gin.H{"message": err.Error()})
+			return
+		}
+		data["name"], data["slug"] = updateData.Name, slug
+	}
+
+	if len(data) == 0 {
+		c.IndentedJSON(http.StatusNoContent, 0)
+		return
+	}
+
+	result := UpdatePersonById(id.Hex(), data)
+
+	c.IndentedJSON(http.StatusNoContent, result)
+}
+
+// DeletePerson puts a person with all its facets in the trash, removing it from the films it is credited on
+func DeletePerson(c *gin.Context) {
+	id, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		c.IndentedJSON(http.StatusBadRequest, gin.H{"message": err.Error()})
+		return
+	}
+
+	result, err := trashPerson(FindPerson(bson.M{"_id": id}))
+	if err != nil {
+		c.IndentedJSON(http.StatusInternalServerError, gin.H{"message": err.Error()})
+		return
+	}
+
+	if result == 0 {
+		c.IndentedJSON(http.StatusNotModified, gin.H{"message": "No person with the specified id"})
+		return
+	}
+
+	c.IndentedJSON(http.StatusNoContent, result)
+}
+
+// MergePerson merges the duplicate person given in the body into the person of the url
+func MergePerson(c *gin.Context) {
+	var req MergeReq
+	if err := c.BindJSON(&req); err != nil {
+		c.IndentedJSON(http.StatusBadRequest, gin.H{"message": "JSON is invalid"})
+		return
+	}
+
+	id, err := primitive.ObjectIDFromHex(c.Param("id"))
+	duplicateId, duplicateErr := primitive.ObjectIDFromHex(req.Duplicate)
+	if err != nil || duplicateErr != nil || id == duplicateId {
+		c.IndentedJSON(http.StatusBadRequest, gin.H{"message": "Id is invalid"})
+		return
+	}
+
+	target := FindPerson(bson.M{"_id": id})
+	duplicate := FindPerson(bson.M{"_id": duplicateId})
+	if target.Id.IsZero() || duplicate.Id.IsZero() {
+		c.IndentedJSON(http.StatusNotFound, gin.H{"message": "Person not found"})
+		return
+	}
+
+	duplicateDocument := findDocument(personColl, req.Duplicate)
+	merged, err := MergePeople(target, duplicate)
+	if err != nil {
+		c.IndentedJSON(http.StatusInternalServerError, gin.H{"message": err.Error()})
+		return
+	}
+
+	// The Audit middleware records the change of the target, and the duplicate is deleted by the merge
+	if err = newAuditTrail(c).record(http.StatusOK, "person", req.Duplicate, duplicateDocument, nil); err != nil {
+		c.IndentedJSON(http.StatusInternalServerError, gin.H{"message": err.Error()})
+		return
+	}
+
+	c.IndentedJSON(http.StatusOK, merged)
+}
+
+func areFacetsValid(facets []string) (bool, gin.H) {
+	for i, facet := range facets {
+		if !containsString(personFacets, facet) {
+			return false, gin.H{"message": fmt.Sprintf("Facet no. %v is invalid", i)}
+		}
+	}
+	return true, nil
+}
+
+// personFacetFilms returns the films of a person for the given facet
+func personFacetFilms(person Person, facet string) []string {
+	switch facet {
+	case actorFacet:
+		return person.ActedFilms
+	case directorFacet:
+		return person.DirectedFilms
+	}
+	return person.Films
+}
+
+// isDuplicatePerson tells whether people with the given name already exist, and answers with a conflict listing them.
+// Adding the person anyway is done with ?force=true.
+func isDuplicatePerson(c *gin.Context, name string) bool {
+	if c.Query("force") == "true" {
+		return false
+	}
+
+	namesakes := FindNamesakes(name)
+	if len(namesakes) == 0 {
+		return false
+	}
+
+	c.IndentedJSON(http.StatusConflict, gin.H{
+		"message":    fmt.Sprintf("%v already exists, add ?force=true to create another person", name),
+		"candidates": namesakes,
+	})
+	return true
+}
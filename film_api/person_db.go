@@ -0,0 +1,395 @@
+package film_api
+
+import (
+	"context"
+	"filmflix/db_connection"
+	"fmt"
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+var personColl *mongo.Collection
+
+// InitPersonCollection initializes the people collection, which also backs the actors and directors
+func InitPersonCollection(client *mongo.Client) {
+	personColl = db_connection.GetCollection(client, "films", "people")
+	actorColl = personColl
+	directorColl = personColl
+	createSlugIndex(personColl)
+}
+
+// withFacet returns a copy of the filter only matching the people having the given facet
+func withFacet(filter bson.M, facet string) bson.M {
+	facetFilter := bson.M{"facets": facet}
+	for key, value := range filter {
+		facetFilter[key] = value
+	}
+	if _, found := filter["facets"]; found {
+		facetFilter["$and"] = []bson.M{{"facets": filter["facets"]}, {"facets": facet}}
+		delete(facetFilter, "facets")
+	}
+
+	return facetFilter
+}
+
+func FindPeople(filter bson.M, maxCount int) []Person {
+	var results []Person
+	limit := int64(maxCount)
+	cursor, err := personColl.Find(context.TODO(), notDeleted(filter), &options.FindOptions{Limit: &limit, Sort: bson.M{"name": 1}})
+	if err != nil {
+		panic(err)
+	}
+
+	if err = cursor.All(context.TODO(), &results); err != nil {
+		panic(err)
+	}
+
+	return results
+}
+
+func FindPerson(filter bson.M) Person {
+	var person Person
+	err := personColl.FindOne(context.TODO(), notDeleted(filter)).Decode(&person)
+
+	if err == mongo.ErrNoDocuments {
+		fmt.Printf("No document was found\n")
+		return person
+	}
+	if err != nil {
+		panic(err)
+	}
+
+	return person
+}
+
+// FindPersonById returns the person with the given id, following the merges
+func FindPersonById(id primitive.ObjectID) Person {
+	person := FindPerson(bson.M{"_id": id})
+	if person.Id.IsZero() {
+		if target := FindRedirectTarget("person", id.Hex()); len(target) > 0 {
+			targetId, _ := primitive.ObjectIDFromHex(target)
+			person = FindPerson(bson.M{"_id": targetId})
+		}
+	}
+
+	return person
+}
+
+func AddPerson(person Person) (Person, error) {
+	person.Id = primitive.NewObjectID()
+	person.Slug = UniqueSlug("person", slugify(person.Name), person.Id)
+	person.Films, person.ActedFilms, person.DirectedFilms = []string{}, []string{}, []string{}
+
+	_, err := personColl.InsertOne(context.TODO(), person)
+	// Another person may have taken the slug since it was chosen
+	if mongo.IsDuplicateKeyError(err) {
+		person.Slug = UniqueSlug("person", slugify(person.Name), person.Id)
+		_, err = personColl.InsertOne(context.TODO(), person)
+	}
+	if err != nil {
+		return Person{}, err
+	}
+
+	catalogueChanged("person", person.Id.Hex())
+
+	return person, nil
+}
+
+func UpdatePersonById(idString string, data interface{}) int64 {
+	id, _ := primitive.ObjectIDFromHex(idString)
+
+	result, err := personColl.UpdateOne(context.TODO(), notDeleted(bson.M{"_id": id}), bson.M{"$set": data})
+	if err != nil {
+		panic(err)
+	}
+
+	catalogueChanged("person", idString)
+
+	return result.ModifiedCount
+}
+
+// AddFilmsToPerson adds films to the crew films of a person, who gets the crew facet
+func AddFilmsToPerson(idString string, films []string) (int64, error) {
+	id, err := primitive.ObjectIDFromHex(idString)
+	if err != nil {
+		return 0, err
+	}
+
+	result, err := personColl.UpdateOne(context.TODO(), bson.M{"_id": id}, bson.M{"$addToSet": bson.M{"films": bson.M{"$each": films}, "facets": crewFacet}})
+	if err != nil {
+		return 0, err
+	}
+
+	catalogueChanged("person", idString)
+
+	return result.ModifiedCount, nil
+}
+
+func RemoveFilmsFromPerson(idString string, films []string) (int64, error) {
+	id, err := primitive.ObjectIDFromHex(idString)
+	if err != nil {
+		return 0, err
+	}
+
+	result, err := personColl.UpdateOne(context.TODO(), bson.M{"_id": id}, bson.M{"$pull": bson.M{"films": bson.M{"$in": films}}})
+	if err != nil {
+		return 0, err
+	}
+
+	catalogueChanged("person", idString)
+
+	return result.ModifiedCount, nil
+}
+
+// RemovePersonFacet removes a facet and the films of this facet from a person, and deletes the person if it has no facet left.
+// It returns the number of modified people.
+func RemovePersonFacet(idString string, facet string) int64 {
+	id, _ := primitive.ObjectIDFromHex(idString)
+
+	result, err := personColl.UpdateOne(context.TODO(), bson.M{"_id": id, "facets": facet}, bson.M{
+		"$pull":  bson.M{"facets": facet},
+		"$unset": bson.M{facetFilmsField(facet): ""},
+	})
+	if err != nil {
+		panic(err)
+	}
+
+	if _, err := personColl.DeleteOne(context.TODO(), bson.M{"_id": id, "facets": bson.M{"$size": 0}}); err != nil {
+		panic(err)
+	}
+
+	catalogueChanged("person", idString)
+
+	return result.ModifiedCount
+}
+
+// facetFilmsField returns the field holding the films of a person for the given facet
+func facetFilmsField(facet string) string {
+	switch facet {
+	case actorFacet:
+		return "acted_films"
+	case directorFacet:
+		return "directed_films"
+	}
+	return "films"
+}
+
+func ArePeopleIdsValid(ids []string) (bool, gin.H) {
+	tempIds := make(map[string]struct{})
+	for i, id := range ids {
+		tempIds[id] = struct{}{}
+		if !primitive.IsValidObjectID(id) {
+			return false, gin.H{"message": fmt.Sprintf("Id of person no. %v is invalid", i)}
+		}
+	}
+
+	peopleIds := make([]primitive.ObjectID, 0, len(tempIds))
+	for k := range tempIds {
+		id, _ := primitive.ObjectIDFromHex(k)
+		peopleIds = append(peopleIds, id)
+	}
+
+	result := FindPeople(bson.M{"_id": bson.M{"$in": peopleIds}}, len(peopleIds))
+
+	if len(result) != len(peopleIds) {
+		return false, gin.H{"message": "At least one person id does not exists"}
+	}
+	return true, nil
+}
+
+// MergePeople merges the duplicate person into the target one: the target gets the facets and the films of the duplicate,
+// all the credits, roles, directions and awards of the duplicate are given to the target and the duplicate id is redirected to the target
+func MergePeople(target Person, duplicate Person) (Person, error) {
+	targetId, duplicateId := target.Id.Hex(), duplicate.Id.Hex()
+
+	target.Facets = append(target.Facets, difference(duplicate.Facets, target.Facets)...)
+	target.ActedFilms = append(target.ActedFilms, difference(duplicate.ActedFilms, target.ActedFilms)...)
+	target.DirectedFilms = append(target.DirectedFilms, difference(duplicate.DirectedFilms, target.DirectedFilms)...)
+	target.Films = append(target.Films, difference(duplicate.Films, target.Films)...)
+	UpdatePersonById(targetId, bson.M{
+		"facets":         target.Facets,
+		"acted_films":    nonNil(target.ActedFilms),
+		"directed_films": nonNil(target.DirectedFilms),
+		"films":          nonNil(target.Films),
+	})
+
+	// The credits of the duplicate are replaced along with its roles and directions
+	if _, err := ReplaceActorInRoles(duplicateId, targetId); err != nil {
+		return Person{}, err
+	}
+	if _, err := ReplaceDirectorInFilms(duplicateId, targetId); err != nil {
+		return Person{}, err
+	}
+
+	for _, kind := range []string{"actor", "director"} {
+		if _, err := ReplacePersonInAwards(kind, duplicateId, targetId); err != nil {
+			return Person{}, err
+		}
+	}
+
+	DeleteItemById(personColl, duplicateId)
+
+	if err := AddRedirect("person", duplicateId, targetId); err != nil {
+		return Person{}, err
+	}
+
+	// The slug of the duplicate may be the one of another person if the duplicate comes from the former collections
+	if owner := FindIdBySlug("person", duplicate.Slug); len(duplicate.Slug) > 0 && (owner.IsZero() || owner == target.Id) {
+		if err := AddSlugHistory("person", duplicate.Slug, targetId); err != nil {
+			return Person{}, err
+		}
+	}
+
+	return target, nil
+}
+
+func nonNil(a []string) []string {
+	if a == nil {
+		return []string{}
+	}
+	return a
+}
+
+// legacyPerson is an actor or a director of the collections used before the people collection
+type legacyPerson struct {
+	Id    primitive.ObjectID `bson:"_id"`
+	Slug  string             `bson:"slug,omitempty"`
+	Name  string             `bson:"name"`
+	Films []string           `bson:"films"`
+}
+
+// MigratePeople moves the actors and directors of the former actors and directors collections to the people collection.
+// A director is merged into the single actor with the same name who played in one of its films, the director id being
+// redirected to the person, and each merge is logged. The other directors having namesake actors are migrated as is and logged
+// as merge candidates, to be merged with POST /api/people/<id>/merge if they are the same person.
+// The migrated items are removed from the former collections, so that the migration can be resumed and does nothing once done.
+func MigratePeople(client *mongo.Client) {
+	legacyActorColl := db_connection.GetCollection(client, "films", "actors")
+	legacyDirectorColl := db_connection.GetCollection(client, "films", "directors")
+
+	// The people created before the facets existed are crew members
+	if _, err := personColl.UpdateMany(context.TODO(), bson.M{"facets": bson.M{"$exists": false}}, bson.M{"$set": bson.M{"facets": []string{crewFacet}}}); err != nil {
+		panic(err)
+	}
+
+	for _, actor := range findLegacyPeople(legacyActorColl) {
+		if FindPerson(bson.M{"_id": actor.Id}).Id.IsZero() {
+			insertLegacyPerson(actor, actorFacet)
+		}
+		DeleteItemById(legacyActorColl, actor.Id.Hex())
+	}
+
+	directors := findLegacyPeople(legacyDirectorColl)
+	if len(directors) > 0 {
+		actorsByName := map[string][]Person{}
+		for _, person := range FindPeople(bson.M{"facets": actorFacet}, 0) {
+			name := foldText(person.Name)
+			actorsByName[name] = append(actorsByName[name], person)
+		}
+
+		for _, director := range directors {
+			if FindPerson(bson.M{"_id": director.Id}).Id.IsZero() {
+				mergeLegacyDirector(director, actorsByName[foldText(director.Name)])
+			}
+			DeleteItemById(legacyDirectorColl, director.Id.Hex())
+		}
+	}
+
+	// The slugs of the actors and directors now share the namespace of the people
+	entries := []SlugHistoryEntry{}
+	cursor, err := slugHistoryColl.Find(context.TODO(), bson.M{"kind": bson.M{"$in": []string{"actor", "director"}}})
+	if err != nil {
+		panic(err)
+	}
+	if err = cursor.All(context.TODO(), &entries); err != nil {
+		panic(err)
+	}
+	for _, entry := range entries {
+		if len(FindSlugHistoryTarget("person", entry.Slug)) == 0 {
+			if err := AddSlugHistory("person", entry.Slug, entry.Target); err != nil {
+				panic(err)
+			}
+		}
+		if _, err := slugHistoryColl.DeleteOne(context.TODO(), bson.M{"_id": entry.Id}); err != nil {
+			panic(err)
+		}
+	}
+}
+
+func findLegacyPeople(collection *mongo.Collection) []legacyPerson {
+	results := []legacyPerson{}
+	cursor, err := collection.Find(context.TODO(), bson.M{})
+	if err != nil {
+		panic(err)
+	}
+
+	if err = cursor.All(context.TODO(), &results); err != nil {
+		panic(err)
+	}
+
+	return results
+}
+
+// insertLegacyPerson adds a former actor or director to the people collection, keeping its id
+func insertLegacyPerson(legacy legacyPerson, facet string) {
+	person := Person{Id: legacy.Id, Name: legacy.Name, Facets: []string{facet}, Films: []string{}, ActedFilms: []string{}, DirectedFilms: []string{}}
+	if facet == actorFacet {
+		person.ActedFilms = nonNil(legacy.Films)
+	} else {
+		person.DirectedFilms = nonNil(legacy.Films)
+	}
+
+	base := legacy.Slug
+	if len(base) == 0 {
+		base = slugify(legacy.Name)
+	}
+	person.Slug = UniqueSlug("person", base, legacy.Id)
+
+	if _, err := personColl.InsertOne(context.TODO(), person); err != nil {
+		panic(err)
+	}
+
+	// The slug may have been taken by a person of the other collection
+	if len(legacy.Slug) > 0 && legacy.Slug != person.Slug {
+		if err := AddSlugHistory("person", legacy.Slug, legacy.Id.Hex()); err != nil {
+			panic(err)
+		}
+	}
+
+	catalogueChanged("person", person.Id.Hex())
+}
+
+// mergeLegacyDirector adds a former director to the people collection, or merges it into the only actor with the same name
+// who played in one of its films, two people being able to share a name
+func mergeLegacyDirector(director legacyPerson, namesakes []Person) {
+	var matches []Person
+	for _, person := range namesakes {
+		if len(intersection(person.ActedFilms, director.Films)) > 0 {
+			matches = append(matches, person)
+		}
+	}
+
+	if len(matches) != 1 {
+		insertLegacyPerson(director, directorFacet)
+		for _, person := range namesakes {
+			fmt.Printf("Director %v (%v) may be the actor %v, merge them with POST /api/people/%v/merge if so\n", director.Id.Hex(), director.Name, person.Id.Hex(), person.Id.Hex())
+		}
+		return
+	}
+
+	// The director is first inserted under its own id so that it is merged like any other person
+	target := FindPerson(bson.M{"_id": matches[0].Id})
+	duplicate := Person{Id: director.Id, Facets: []string{directorFacet}, DirectedFilms: nonNil(director.Films), Films: []string{}, ActedFilms: []string{}}
+	if _, err := personColl.InsertOne(context.TODO(), duplicate); err != nil {
+		panic(err)
+	}
+	duplicate.Slug = director.Slug
+
+	if _, err := MergePeople(target, duplicate); err != nil {
+		panic(err)
+	}
+	fmt.Printf("Director %v (%v) was merged into the actor %v\n", director.Id.Hex(), director.Name, target.Id.Hex())
+}
@@ -0,0 +1,171 @@
+package film_api
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"filmflix/asset_storage"
+	"fmt"
+	"github.com/gin-gonic/gin"
+	"image"
+	"image/draw"
+	"image/jpeg"
+	"image/png"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strings"
+)
+
+// maxPosterSize is the maximum size in bytes of an uploaded poster
+const maxPosterSize = 10 << 20
+
+// maxPosterPixels is the maximum number of pixels of an uploaded poster, to refuse images too big to be decoded in memory
+const maxPosterPixels = 40000000
+
+// posterJPEGQuality is the quality of the resized variants of the JPEG posters
+const posterJPEGQuality = 85
+
+// posterVariantsWidths maps the name of each resized variant of a poster to its width in pixels
+var posterVariantsWidths = map[string]int{
+	"small":  185,
+	"medium": 342,
+	"large":  780,
+}
+
+// originalPosterVariant is the name of the uploaded poster, stored as is
+const originalPosterVariant = "original"
+
+type storedPoster struct {
+	name string
+	data []byte
+}
+
+// readPoster returns the uploaded poster, sent in the "poster" field of a multipart form or as the body of the request
+func readPoster(c *gin.Context) ([]byte, error) {
+	// The multipart form adds a few headers around the poster
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, maxPosterSize+4096)
+
+	var reader io.Reader = c.Request.Body
+	if strings.HasPrefix(c.ContentType(), "multipart/form-data") {
+		header, err := c.FormFile("poster")
+		if err != nil {
+			return nil, err
+		}
+		file, err := header.Open()
+		if err != nil {
+			return nil, err
+		}
+		defer file.Close()
+		reader = file
+	}
+
+	data, err := ioutil.ReadAll(reader)
+	if err != nil {
+		return nil, fmt.Errorf("the poster can't be read, it must be at most %d MB", maxPosterSize>>20)
+	}
+	if len(data) == 0 {
+		return nil, fmt.Errorf("the poster is empty")
+	}
+	if len(data) > maxPosterSize {
+		return nil, fmt.Errorf("the poster is too big, it must be at most %d MB", maxPosterSize>>20)
+	}
+
+	return data, nil
+}
+
+// posterFiles decodes a JPEG or PNG poster and returns the files to store, named after the film and the content of the
+// poster so that a new poster never has the url of an old one, and the url of each variant.
+func posterFiles(filmId string, data []byte) ([]storedPoster, map[string]string, error) {
+	config, format, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil || (format != "jpeg" && format != "png") {
+		return nil, nil, fmt.Errorf("the poster must be a JPEG or PNG image")
+	}
+	if config.Width*config.Height > maxPosterPixels {
+		return nil, nil, fmt.Errorf("the poster is too big, it must have at most %d pixels", maxPosterPixels)
+	}
+
+	poster, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, nil, fmt.Errorf("the poster can't be decoded: %s", err.Error())
+	}
+
+	extension := ".jpg"
+	if format == "png" {
+		extension = ".png"
+	}
+	hash := sha256.Sum256(data)
+	prefix := posterDirectory(filmId) + hex.EncodeToString(hash[:6]) + "-"
+
+	files := []storedPoster{{name: prefix + originalPosterVariant + extension, data: data}}
+	for variant, width := range posterVariantsWidths {
+		var encoded bytes.Buffer
+		resized := resizeToWidth(poster, width)
+		if format == "png" {
+			err = png.Encode(&encoded, resized)
+		} else {
+			err = jpeg.Encode(&encoded, resized, &jpeg.Options{Quality: posterJPEGQuality})
+		}
+		if err != nil {
+			return nil, nil, err
+		}
+		files = append(files, storedPoster{name: prefix + variant + extension, data: encoded.Bytes()})
+	}
+
+	urls := make(map[string]string, len(files))
+	for _, file := range files {
+		variant := strings.TrimSuffix(strings.TrimPrefix(file.name, prefix), extension)
+		urls[variant] = asset_storage.GetStorage().URL(file.name)
+	}
+
+	return files, urls, nil
+}
+
+// posterDirectory is the directory of the storage holding the posters of a film
+func posterDirectory(filmId string) string {
+	return "posters/" + filmId + "/"
+}
+
+// resizeToWidth scales an image down to the given width, keeping its aspect ratio, by averaging the source pixels
+// covered by each pixel of the result. Images narrower than the width are kept as they are.
+func resizeToWidth(source image.Image, width int) image.Image {
+	bounds := source.Bounds()
+	if bounds.Dx() <= width {
+		return source
+	}
+	height := bounds.Dy() * width / bounds.Dx()
+	if height < 1 {
+		height = 1
+	}
+
+	// The source is converted to premultiplied RGBA so that its pixels can be read directly
+	rgba := image.NewRGBA(image.Rect(0, 0, bounds.Dx(), bounds.Dy()))
+	draw.Draw(rgba, rgba.Bounds(), source, bounds.Min, draw.Src)
+
+	result := image.NewRGBA(image.Rect(0, 0, width, height))
+	for y := 0; y < height; y++ {
+		y0, y1 := y*bounds.Dy()/height, (y+1)*bounds.Dy()/height
+		for x := 0; x < width; x++ {
+			x0, x1 := x*bounds.Dx()/width, (x+1)*bounds.Dx()/width
+
+			var sum [4]int
+			for sy := y0; sy < y1; sy++ {
+				row := rgba.Pix[sy*rgba.Stride+x0*4 : sy*rgba.Stride+x1*4]
+				for i := 0; i < len(row); i += 4 {
+					sum[0] += int(row[i])
+					sum[1] += int(row[i+1])
+					sum[2] += int(row[i+2])
+					sum[3] += int(row[i+3])
+				}
+			}
+
+			count := (x1 - x0) * (y1 - y0)
+			offset := y*result.Stride + x*4
+			for i := range sum {
+				result.Pix[offset+i] = uint8(sum[i] / count)
+			}
+		}
+	}
+
+	return result
+}
@@ -0,0 +1,212 @@
+package film_api
+
+import (
+	"fmt"
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// RatingSource is the rating of a film given by a source, Normalized being the value brought between 0 and 100
+type RatingSource struct {
+	Value      float64   `json:"value" bson:"value"`
+	Scale      float64   `json:"scale" bson:"scale"` // Scale is the best value of the source, like 100 for a percentage or 10 for a mark out of 10
+	Normalized float64   `json:"normalized" bson:"normalized"`
+	UpdatedAt  time.Time `json:"updated_at" bson:"updated_at"`
+}
+
+// Rating sources: the critics score, which was the former rt_score, the audience score, the editorial score of the
+// catalogue and the average of the user reviews, which is only updated by the reviews
+const (
+	criticsRating   = "critics"
+	audienceRating  = "audience"
+	editorialRating = "editorial"
+	usersRating     = "users"
+)
+
+var ratingSources = []string{criticsRating, audienceRating, editorialRating, usersRating}
+
+// ratingScoreStage sets the rating score of a film, the average of the normalized values of its rating sources
+var ratingScoreStage = bson.M{"$set": bson.M{"rating_score": bson.M{"$avg": bson.M{"$map": bson.M{
+	"input": bson.M{"$objectToArray": bson.M{"$ifNull": []interface{}{"$ratings", bson.M{}}}},
+	"in":    "$$this.v.normalized",
+}}}}}
+
+func newRatingSource(value float64, scale float64) RatingSource {
+	return RatingSource{Value: value, Scale: scale, Normalized: value / scale * 100, UpdatedAt: time.Now()}
+}
+
+func isRatingSourceValid(rating RatingSource) (bool, gin.H) {
+	if rating.Scale <= 0 {
+		return false, gin.H{"message": "The scale must be positive"}
+	}
+	if rating.Value < 0 || rating.Value > rating.Scale {
+		return false, gin.H{"message": "The value must be between 0 and the scale"}
+	}
+	return true, nil
+}
+
+// parseCriticsScore reads the former rt_score, a percentage
+func parseCriticsScore(rtScore string) (RatingSource, error) {
+	value, err := strconv.ParseFloat(rtScore, 64)
+	if err != nil {
+		return RatingSource{}, fmt.Errorf("rt_score must be a number")
+	}
+
+	rating := newRatingSource(value, 100)
+	if valid, _ := isRatingSourceValid(rating); !valid {
+		return RatingSource{}, fmt.Errorf("rt_score must be between 0 and 100")
+	}
+	return rating, nil
+}
+
+// formatCriticsScore returns the rt_score matching the critics rating
+func formatCriticsScore(rating RatingSource) string {
+	return strconv.FormatFloat(math.Round(rating.Normalized), 'f', -1, 64)
+}
+
+// normalizeRatings checks the rating sources given for a new film, adds the critics rating from the rt_score and
+// computes the rating score. The users rating can't be given, it comes from the reviews.
+func normalizeRatings(film *Film) (bool, gin.H) {
+	ratings := make(map[string]RatingSource, len(film.Ratings))
+	for source, rating := range film.Ratings {
+		if !containsString(ratingSources, source) || source == usersRating {
+			return false, gin.H{"message": "Rating source " + source + " is invalid"}
+		}
+		if valid, msg := isRatingSourceValid(rating); !valid {
+			return false, msg
+		}
+		ratings[source] = newRatingSource(rating.Value, rating.Scale)
+	}
+
+	if critics, found := ratings[criticsRating]; found {
+		film.Rating = formatCriticsScore(critics)
+	} else if len(film.Rating) > 0 {
+		critics, err := parseCriticsScore(film.Rating)
+		if err != nil {
+			return false, gin.H{"message": err.Error()}
+		}
+		ratings[criticsRating] = critics
+	}
+
+	film.Ratings = ratings
+	film.RatingScore = 0
+	for _, rating := range ratings {
+		film.RatingScore += rating.Normalized / float64(len(ratings))
+	}
+
+	return true, nil
+}
+
+func GetFilmRatings(c *gin.Context) {
+	id, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		c.IndentedJSON(http.StatusBadRequest, gin.H{"message": "Id is invalid"})
+		return
+	}
+
+	film := FindFilm(bson.M{"_id": id})
+	if len(film.Title) == 0 {
+		c.IndentedJSON(http.StatusNotFound, gin.H{"message": "Film not found"})
+		return
+	}
+
+	if film.Ratings == nil {
+		film.Ratings = map[string]RatingSource{}
+	}
+	c.IndentedJSON(http.StatusOK, gin.H{"ratings": film.Ratings, "rating_score": film.RatingScore})
+}
+
+// PutFilmRating sets the value and the scale of a rating source of a film
+func PutFilmRating(c *gin.Context) {
+	source, ok := editableRatingSource(c)
+	if !ok {
+		return
+	}
+
+	var rating RatingSource
+	if err := c.BindJSON(&rating); err != nil {
+		c.IndentedJSON(http.StatusBadRequest, gin.H{"message": "JSON is invalid"})
+		return
+	}
+	if valid, msg := isRatingSourceValid(rating); !valid {
+		c.IndentedJSON(http.StatusBadRequest, msg)
+		return
+	}
+	rating = newRatingSource(rating.Value, rating.Scale)
+
+	result, err := SetFilmRating(c.Param("id"), source, &rating)
+	if err != nil {
+		c.IndentedJSON(http.StatusBadRequest, gin.H{"message": err.Error()})
+		return
+	}
+	if result == 0 {
+		c.IndentedJSON(http.StatusNotFound, gin.H{"message": "Film not found"})
+		return
+	}
+
+	c.IndentedJSON(http.StatusOK, rating)
+}
+
+func DeleteFilmRating(c *gin.Context) {
+	source, ok := editableRatingSource(c)
+	if !ok {
+		return
+	}
+
+	result, err := SetFilmRating(c.Param("id"), source, nil)
+	if err != nil {
+		c.IndentedJSON(http.StatusBadRequest, gin.H{"message": err.Error()})
+		return
+	}
+
+	c.IndentedJSON(http.StatusNoContent, result)
+}
+
+// editableRatingSource returns the rating source of the url, answering with an error if it is unknown or computed from the reviews
+func editableRatingSource(c *gin.Context) (string, bool) {
+	source := c.Param("source")
+	if !containsString(ratingSources, source) {
+		c.IndentedJSON(http.StatusBadRequest, gin.H{"message": "Rating source is invalid"})
+		return "", false
+	}
+	if source == usersRating {
+		c.IndentedJSON(http.StatusBadRequest, gin.H{"message": "The users rating is computed from the reviews"})
+		return "", false
+	}
+	return source, true
+}
+
+// ratingsFilter adds to the filter of the films the bounds given with ?min_rating=<0-100>&max_rating=<0-100>, which apply
+// to the normalized value of the source given with ?rating_source=<source>, or to the rating score if there is none
+func ratingsFilter(c *gin.Context, filter bson.M) bool {
+	field := "rating_score"
+	if source := c.Query("rating_source"); len(source) > 0 {
+		if !containsString(ratingSources, source) {
+			c.IndentedJSON(http.StatusBadRequest, gin.H{"message": "rating_source is invalid"})
+			return false
+		}
+		field = "ratings." + source + ".normalized"
+	}
+
+	bounds := bson.M{}
+	for param, operator := range map[string]string{"min_rating": "$gte", "max_rating": "$lte"} {
+		if v := c.Query(param); len(v) > 0 {
+			value, err := strconv.ParseFloat(v, 64)
+			if err != nil {
+				c.IndentedJSON(http.StatusBadRequest, gin.H{"message": param + " must be a number between 0 and 100"})
+				return false
+			}
+			bounds[operator] = value
+		}
+	}
+	if len(bounds) > 0 {
+		filter[field] = bounds
+	}
+
+	return true
+}
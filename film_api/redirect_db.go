@@ -0,0 +1,60 @@
+package film_api
+
+import (
+	"context"
+	"filmflix/db_connection"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"time"
+)
+
+// Redirect records that an item was merged into another one, so that its old id keeps resolving
+type Redirect struct {
+	From      string    `json:"from" bson:"_id"`
+	To        string    `json:"to" bson:"to"`
+	Kind      string    `json:"kind" bson:"kind"` // Kind is the kind of the merged item, "person" or, for the merges done before the people collection, "actor" or "director"
+	CreatedAt time.Time `json:"created_at" bson:"created_at"`
+}
+
+var redirectColl *mongo.Collection
+
+func InitRedirectCollection(client *mongo.Client) {
+	redirectColl = db_connection.GetCollection(client, "films", "redirects")
+}
+
+// redirectKinds returns the kinds of the redirects which apply to the items of the given kind,
+// the actors and directors being all people
+func redirectKinds(kind string) []string {
+	if kind == "person" || kind == "actor" || kind == "director" {
+		return []string{"person", "actor", "director"}
+	}
+	return []string{kind}
+}
+
+// AddRedirect makes the given id of an item resolve to the id of the item it was merged into
+func AddRedirect(kind string, from string, to string) error {
+	// The items previously merged into the removed one now resolve directly to the new one
+	_, err := redirectColl.UpdateMany(context.TODO(), bson.M{"kind": bson.M{"$in": redirectKinds(kind)}, "to": from}, bson.M{"$set": bson.M{"to": to}})
+	if err != nil {
+		return err
+	}
+
+	_, err = redirectColl.ReplaceOne(context.TODO(), bson.M{"_id": from}, Redirect{From: from, To: to, Kind: kind, CreatedAt: time.Now()}, options.Replace().SetUpsert(true))
+	return err
+}
+
+// FindRedirectTarget returns the id of the item the given id was merged into, or an empty string if there is none
+func FindRedirectTarget(kind string, from string) string {
+	var redirect Redirect
+	err := redirectColl.FindOne(context.TODO(), bson.M{"_id": from, "kind": bson.M{"$in": redirectKinds(kind)}}).Decode(&redirect)
+
+	if err == mongo.ErrNoDocuments {
+		return ""
+	}
+	if err != nil {
+		panic(err)
+	}
+
+	return redirect.To
+}
@@ -0,0 +1,212 @@
+package film_api
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"github.com/gin-gonic/gin"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Precisions of the partial dates, a release date is often only known up to its year or its month
+const (
+	yearPrecision  = "year"
+	monthPrecision = "month"
+	dayPrecision   = "day"
+)
+
+// PartialDate is a date known up to its year, its month or its day. Time holds the first day of the known period.
+type PartialDate struct {
+	Time      time.Time `bson:"date"`
+	Precision string    `bson:"precision"`
+}
+
+// RegionalRelease is the release of a film in a country
+type RegionalRelease struct {
+	Country string      `json:"country" bson:"country"` // Country is the ISO 3166-1 alpha-2 code of the country, like "JP" or "FR"
+	Date    PartialDate `json:"date" bson:"date"`
+	Type    string      `json:"type" bson:"type"`
+}
+
+// releaseTypes are the kinds of the regional releases
+var releaseTypes = []string{"premiere", "festival", "limited", "theatrical", "digital", "physical", "tv"}
+
+var (
+	yearRegexp    = regexp.MustCompile(`\b(\d{4})\b`)
+	countryRegexp = regexp.MustCompile(`^[A-Z]{2}$`)
+)
+
+// partialDateLayouts are the accepted formats of the dates, with the precision they give
+var partialDateLayouts = []struct {
+	layout    string
+	precision string
+}{
+	{"2006", yearPrecision},
+	{"2006-01", monthPrecision},
+	{"January 2006", monthPrecision},
+	{"Jan 2006", monthPrecision},
+	{"2006-01-02", dayPrecision},
+	{"January 2, 2006", dayPrecision},
+	{"Jan 2, 2006", dayPrecision},
+	{"2 January 2006", dayPrecision},
+	{"2 Jan 2006", dayPrecision},
+	{time.RFC3339, dayPrecision},
+}
+
+// parsePartialDate parses a date like "1988", "1988-04" or "1988-04-16"
+func parsePartialDate(text string) (PartialDate, error) {
+	text = strings.TrimSpace(text)
+	for _, format := range partialDateLayouts {
+		if date, err := time.Parse(format.layout, text); err == nil {
+			return newPartialDate(date, format.precision), nil
+		}
+	}
+
+	return PartialDate{}, fmt.Errorf("the date %q is invalid, it must be like 1988, 1988-04 or 1988-04-16", text)
+}
+
+// newPartialDate returns the date truncated to the given precision
+func newPartialDate(date time.Time, precision string) PartialDate {
+	year, month, day := date.Date()
+	switch precision {
+	case yearPrecision:
+		month, day = time.January, 1
+	case monthPrecision:
+		day = 1
+	}
+
+	return PartialDate{Time: time.Date(year, month, day, 0, 0, 0, 0, time.UTC), Precision: precision}
+}
+
+// IsZero tells whether the date is unknown, it makes the omitempty bson option work for the partial dates
+func (date PartialDate) IsZero() bool {
+	return date.Time.IsZero()
+}
+
+// Year returns the year of the date, or 0 if the date is unknown
+func (date PartialDate) Year() int {
+	if date.IsZero() {
+		return 0
+	}
+	return date.Time.Year()
+}
+
+// String returns the date in the ISO 8601 format, up to its precision
+func (date PartialDate) String() string {
+	if date.IsZero() {
+		return ""
+	}
+
+	switch date.Precision {
+	case yearPrecision:
+		return date.Time.Format("2006")
+	case monthPrecision:
+		return date.Time.Format("2006-01")
+	}
+	return date.Time.Format("2006-01-02")
+}
+
+func (date PartialDate) MarshalJSON() ([]byte, error) {
+	if date.IsZero() {
+		return []byte("null"), nil
+	}
+
+	return json.Marshal(struct {
+		Date      string `json:"date"`
+		Precision string `json:"precision"`
+	}{date.String(), date.Precision})
+}
+
+// UnmarshalJSON reads a date given either as a string, like "1988-04", or as an object with a "date" field
+func (date *PartialDate) UnmarshalJSON(data []byte) error {
+	if bytes.Equal(data, []byte("null")) {
+		*date = PartialDate{}
+		return nil
+	}
+
+	var text string
+	if err := json.Unmarshal(data, &text); err != nil {
+		var object struct {
+			Date string `json:"date"`
+		}
+		if err := json.Unmarshal(data, &object); err != nil {
+			return err
+		}
+		text = object.Date
+	}
+
+	parsed, err := parsePartialDate(text)
+	if err != nil {
+		return err
+	}
+
+	*date = parsed
+	return nil
+}
+
+// parseReleaseYear returns the first four digits number of a free-form release date, or 0 if there is none
+func parseReleaseYear(releaseDate string) int {
+	match := yearRegexp.FindStringSubmatch(releaseDate)
+	if match == nil {
+		return 0
+	}
+
+	year, _ := strconv.Atoi(match[1])
+	return year
+}
+
+// normalizeReleases parses the release date of a film and checks its regional releases, which are sorted by date.
+// The release date is rewritten in the ISO 8601 format.
+func normalizeReleases(film *Film) (bool, gin.H) {
+	if len(film.ReleaseDate) > 0 {
+		release, err := parsePartialDate(film.ReleaseDate)
+		if err != nil {
+			return false, gin.H{"message": err.Error()}
+		}
+		film.Release = release
+		film.ReleaseDate = release.String()
+	}
+
+	for i := range film.RegionalReleases {
+		regional := &film.RegionalReleases[i]
+		regional.Country = strings.ToUpper(strings.TrimSpace(regional.Country))
+		if !countryRegexp.MatchString(regional.Country) {
+			return false, gin.H{"message": fmt.Sprintf("Country of regional release no. %v is invalid", i)}
+		}
+		if !containsString(releaseTypes, regional.Type) {
+			return false, gin.H{"message": fmt.Sprintf("Type of regional release no. %v is invalid", i)}
+		}
+		if regional.Date.IsZero() {
+			return false, gin.H{"message": fmt.Sprintf("Date of regional release no. %v is required", i)}
+		}
+	}
+	sort.SliceStable(film.RegionalReleases, func(i, j int) bool {
+		return film.RegionalReleases[i].Date.Time.Before(film.RegionalReleases[j].Date.Time)
+	})
+
+	return true, nil
+}
+
+// parseYearRange parses a year, like "1988", or a range of years, like "1980-1989"
+func parseYearRange(text string) (int, int, error) {
+	bounds := strings.SplitN(text, "-", 2)
+	from, err := strconv.Atoi(strings.TrimSpace(bounds[0]))
+	if err != nil {
+		return 0, 0, err
+	}
+	to := from
+	if len(bounds) == 2 {
+		if to, err = strconv.Atoi(strings.TrimSpace(bounds[1])); err != nil {
+			return 0, 0, err
+		}
+	}
+	if to < from {
+		return 0, 0, fmt.Errorf("the range of years is empty")
+	}
+
+	return from, to, nil
+}
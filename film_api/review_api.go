@@ -0,0 +1,169 @@
+package film_api
+
+import (
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"net/http"
+	"os"
+	"strconv"
+	"time"
+)
+
+const (
+	minRating = 1
+	maxRating = 10
+)
+
+// defaultMinReviews is the number of reviews from which the average rating of a film weighs more than the mean rating
+// of all the films in the ranking, when the RANKING_MIN_REVIEWS env var is not set
+const defaultMinReviews = 5
+
+type Review struct {
+	Id        primitive.ObjectID `json:"id" bson:"_id,omitempty"`
+	FilmId    string             `json:"film" bson:"film"`
+	UserId    string             `json:"user" bson:"user"`
+	Rating    int                `json:"rating" bson:"rating"` // Rating is between 1 and 10
+	Text      string             `json:"text" bson:"text,omitempty"`
+	CreatedAt time.Time          `json:"created_at" bson:"created_at"`
+	UpdatedAt time.Time          `json:"updated_at" bson:"updated_at"`
+}
+
+type ReviewReq struct {
+	Rating int    `json:"rating"`
+	Text   string `json:"text"`
+}
+
+// RankedFilm is a film with its Bayesian weighted user rating
+type RankedFilm struct {
+	Film           `bson:",inline"`
+	WeightedRating float64 `json:"weighted_rating" bson:"weighted_rating"`
+}
+
+// GetFilmReviews returns the reviews of the film of the url
+func GetFilmReviews(c *gin.Context) {
+	limit := 20
+	if l := c.Query("limit"); len(l) > 0 {
+		limit, _ = strconv.Atoi(l)
+	}
+
+	c.IndentedJSON(http.StatusOK, FindReviews(bson.M{"film": c.Param("id")}, limit))
+}
+
+// GetUserReviews returns the reviews written by the user of the url
+func GetUserReviews(c *gin.Context) {
+	limit := 20
+	if l := c.Query("limit"); len(l) > 0 {
+		limit, _ = strconv.Atoi(l)
+	}
+
+	c.IndentedJSON(http.StatusOK, FindReviews(bson.M{"user": c.Param("id")}, limit))
+}
+
+// PutReview creates or replaces the review of the authenticated user on the film of the url
+func PutReview(c *gin.Context) {
+	user, ok := authenticateUser(c)
+	if !ok {
+		return
+	}
+
+	var req ReviewReq
+	if err := c.BindJSON(&req); err != nil {
+		c.IndentedJSON(http.StatusBadRequest, gin.H{"message": "JSON is invalid"})
+		return
+	}
+
+	if req.Rating < minRating || req.Rating > maxRating {
+		c.IndentedJSON(http.StatusBadRequest, gin.H{"message": "The rating must be between 1 and 10"})
+		return
+	}
+
+	filmId := c.Param("id")
+	if valid, msg := AreFilmsIdsValid([]string{filmId}); !valid {
+		c.IndentedJSON(http.StatusNotFound, msg)
+		return
+	}
+
+	// The creation date is kept when the review is replaced
+	now := time.Now()
+	createdAt := now
+	if existing := FindReview(bson.M{"film": filmId, "user": user.Id.Hex()}); !existing.Id.IsZero() {
+		createdAt = existing.CreatedAt
+	}
+
+	review, replaced, err := SaveReview(Review{
+		FilmId:    filmId,
+		UserId:    user.Id.Hex(),
+		Rating:    req.Rating,
+		Text:      req.Text,
+		CreatedAt: createdAt,
+		UpdatedAt: now,
+	})
+	if err != nil {
+		c.IndentedJSON(http.StatusInternalServerError, gin.H{"message": err.Error()})
+		return
+	}
+
+	isNew := replaced.Id.IsZero()
+	countDelta := 0
+	if isNew {
+		countDelta = 1
+	}
+	if err := UpdateFilmUserRating(filmId, countDelta, req.Rating-replaced.Rating); err != nil {
+		c.IndentedJSON(http.StatusInternalServerError, gin.H{"message": err.Error()})
+		return
+	}
+
+	if isNew {
+		c.IndentedJSON(http.StatusCreated, review)
+	} else {
+		c.IndentedJSON(http.StatusOK, review)
+	}
+}
+
+// DeleteReview deletes the review of the authenticated user on the film of the url
+func DeleteReview(c *gin.Context) {
+	user, ok := authenticateUser(c)
+	if !ok {
+		return
+	}
+
+	review := FindReview(bson.M{"film": c.Param("id"), "user": user.Id.Hex()})
+	if review.Id.IsZero() {
+		c.IndentedJSON(http.StatusNotFound, gin.H{"message": "Review not found"})
+		return
+	}
+
+	result, err := RemoveReview(review)
+	if err != nil {
+		c.IndentedJSON(http.StatusInternalServerError, gin.H{"message": err.Error()})
+		return
+	}
+
+	if result > 0 {
+		if err := UpdateFilmUserRating(review.FilmId, -1, -review.Rating); err != nil {
+			c.IndentedJSON(http.StatusInternalServerError, gin.H{"message": err.Error()})
+			return
+		}
+	}
+
+	c.IndentedJSON(http.StatusNoContent, result)
+}
+
+// GetTopRatedFilms returns the films ranked by their Bayesian weighted user rating
+func GetTopRatedFilms(c *gin.Context) {
+	limit := 20
+	if l := c.Query("limit"); len(l) > 0 {
+		limit, _ = strconv.Atoi(l)
+	}
+	if limit <= 0 {
+		limit = 20
+	}
+
+	minReviews, err := strconv.Atoi(os.Getenv("RANKING_MIN_REVIEWS"))
+	if err != nil || minReviews < 0 {
+		minReviews = defaultMinReviews
+	}
+
+	c.IndentedJSON(http.StatusOK, FindTopRatedFilms(minReviews, limit))
+}
@@ -0,0 +1,173 @@
+package film_api
+
+import (
+	"context"
+	"filmflix/db_connection"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+var reviewColl *mongo.Collection
+
+func InitReviewCollection(client *mongo.Client) {
+	reviewColl = db_connection.GetCollection(client, "films", "reviews")
+
+	// A user has a single review per film
+	_, err := reviewColl.Indexes().CreateOne(context.TODO(), mongo.IndexModel{
+		Keys:    bson.D{{Key: "film", Value: 1}, {Key: "user", Value: 1}},
+		Options: options.Index().SetUnique(true),
+	})
+	if err != nil {
+		panic(err)
+	}
+}
+
+// FindReviews retrieves the reviews matching the filter, except the ones of the films in the trash, the most recent first
+func FindReviews(filter bson.M, maxCount int) []Review {
+	results := []Review{}
+	limit := int64(maxCount)
+	cursor, err := reviewColl.Find(context.TODO(), withoutTrashedFilms(filter), &options.FindOptions{Limit: &limit, Sort: bson.M{"updated_at": -1}})
+	if err != nil {
+		panic(err)
+	}
+
+	if err = cursor.All(context.TODO(), &results); err != nil {
+		panic(err)
+	}
+
+	return results
+}
+
+func FindReview(filter bson.M) Review {
+	var review Review
+	err := reviewColl.FindOne(context.TODO(), filter).Decode(&review)
+
+	if err == mongo.ErrNoDocuments {
+		return review
+	}
+	if err != nil {
+		panic(err)
+	}
+
+	return review
+}
+
+// SaveReview creates or replaces the review of a user on a film, and returns the saved review along with the one it
+// replaced, whose id is zero if there was none. The replaced review is the one actually found by the write, so that the
+// change of the rating of the film is right even when the review is saved twice at once.
+func SaveReview(review Review) (Review, Review, error) {
+	filter := bson.M{"film": review.FilmId, "user": review.UserId}
+
+	// The id of a review is immutable, so the replacement never has one
+	review.Id = primitive.NilObjectID
+
+	var replaced Review
+	opts := options.FindOneAndReplace().SetUpsert(true).SetReturnDocument(options.Before)
+	err := reviewColl.FindOneAndReplace(context.TODO(), filter, review, opts).Decode(&replaced)
+	// Two upserts of the same new review may both insert it, the unique index rejecting the second one which then
+	// replaces the first
+	if mongo.IsDuplicateKeyError(err) {
+		err = reviewColl.FindOneAndReplace(context.TODO(), filter, review, opts).Decode(&replaced)
+	}
+	if err != nil && err != mongo.ErrNoDocuments {
+		return Review{}, Review{}, err
+	}
+
+	return FindReview(filter), replaced, nil
+}
+
+func RemoveReview(review Review) (int64, error) {
+	result, err := reviewColl.DeleteOne(context.TODO(), bson.M{"_id": review.Id})
+	if err != nil {
+		return 0, err
+	}
+
+	return result.DeletedCount, nil
+}
+
+// RemoveReviewsOfFilm deletes the reviews of a film and returns the number of deleted reviews
+func RemoveReviewsOfFilm(filmId string) (int64, error) {
+	result, err := reviewColl.DeleteMany(context.TODO(), bson.M{"film": filmId})
+	if err != nil {
+		return 0, err
+	}
+
+	return result.DeletedCount, nil
+}
+
+// UpdateFilmUserRating applies the change of the reviews of a film to its stored user rating: countDelta is the number
+// of added (or removed if negative) reviews and sumDelta the change of the sum of their ratings
+func UpdateFilmUserRating(idString string, countDelta int, sumDelta int) error {
+	id, err := primitive.ObjectIDFromHex(idString)
+	if err != nil {
+		return err
+	}
+
+	// The update is a pipeline, so that the average is computed from the new count and sum in a single atomic operation
+	_, err = filmColl.UpdateOne(context.TODO(), bson.M{"_id": id}, []bson.M{
+		{"$set": bson.M{
+			"user_rating_count": bson.M{"$add": []interface{}{bson.M{"$ifNull": []interface{}{"$user_rating_count", 0}}, countDelta}},
+			"user_rating_sum":   bson.M{"$add": []interface{}{bson.M{"$ifNull": []interface{}{"$user_rating_sum", 0}}, sumDelta}},
+		}},
+		{"$set": bson.M{"user_rating_average": bson.M{"$cond": []interface{}{
+			bson.M{"$gt": []interface{}{"$user_rating_count", 0}},
+			bson.M{"$divide": []interface{}{"$user_rating_sum", "$user_rating_count"}},
+			0,
+		}}}},
+		// The average of the reviews is the users rating source of the film
+		{"$set": bson.M{"ratings." + usersRating: bson.M{"$cond": []interface{}{
+			bson.M{"$gt": []interface{}{"$user_rating_count", 0}},
+			bson.M{
+				"value":      "$user_rating_average",
+				"scale":      maxRating,
+				"normalized": bson.M{"$multiply": []interface{}{"$user_rating_average", 100.0 / maxRating}},
+				"updated_at": "$$NOW",
+			},
+			"$$REMOVE",
+		}}}},
+		ratingScoreStage,
+	})
+	if err != nil {
+		return err
+	}
+
+	catalogueChanged("film", idString)
+
+	return nil
+}
+
+// FindMeanUserRating returns the mean rating of all the reviews, or 0 if there is none
+func FindMeanUserRating() float64 {
+	var results []struct {
+		Mean float64 `bson:"mean"`
+	}
+	aggregate(reviewColl, []bson.M{{"$group": bson.M{"_id": nil, "mean": bson.M{"$avg": "$rating"}}}}, &results)
+
+	if len(results) == 0 {
+		return 0
+	}
+	return results[0].Mean
+}
+
+// FindTopRatedFilms returns the films with the best Bayesian weighted user rating: the average rating of each film is
+// pulled toward the mean rating of all the reviews, the more so that the film has fewer reviews than minReviews
+func FindTopRatedFilms(minReviews int, maxCount int) []RankedFilm {
+	results := []RankedFilm{}
+	mean := FindMeanUserRating()
+	count := bson.M{"$ifNull": []interface{}{"$user_rating_count", 0}}
+	average := bson.M{"$ifNull": []interface{}{"$user_rating_average", 0}}
+
+	aggregate(filmColl, []bson.M{
+		{"$match": bson.M{"user_rating_count": bson.M{"$gt": 0}}},
+		{"$addFields": bson.M{"weighted_rating": bson.M{"$divide": []interface{}{
+			bson.M{"$add": []interface{}{bson.M{"$multiply": []interface{}{count, average}}, float64(minReviews) * mean}},
+			bson.M{"$add": []interface{}{count, minReviews}},
+		}}}},
+		{"$sort": bson.D{{Key: "weighted_rating", Value: -1}, {Key: "user_rating_count", Value: -1}}},
+		{"$limit": maxCount},
+	}, &results)
+
+	return results
+}
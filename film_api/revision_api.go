@@ -0,0 +1,354 @@
+package film_api
+
+import (
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// initialAction is the action of the revision saving the state of an entity before its first recorded change
+const initialAction = "initial"
+
+// Revision is the full state of a film or of a person after a change, which can be restored
+type Revision struct {
+	Id        primitive.ObjectID `json:"id" bson:"_id,omitempty"`
+	Kind      string             `json:"kind" bson:"kind"` // Kind is film or person, the actors and directors being people
+	EntityId  string             `json:"entity_id" bson:"entity_id"`
+	Number    int                `json:"number" bson:"number"`
+	Time      time.Time          `json:"time" bson:"time"`
+	Actor     AuditActor         `json:"actor" bson:"actor"`
+	RequestId string             `json:"request_id,omitempty" bson:"request_id,omitempty"`
+	Action    string             `json:"action" bson:"action"`
+	Document  bson.M             `json:"document,omitempty" bson:"document,omitempty"`
+	// Roles are the roles of a person on the films when the revision was made, since they are stored in the films
+	Roles []TrashedRole `json:"roles,omitempty" bson:"roles,omitempty"`
+}
+
+// initRevisionRoutes adds the routes of the revisions of the entities of a group, whose restoration requires the scope
+func initRevisionRoutes(routes *gin.RouterGroup, kind string, scope string) {
+	routes.GET("/:id/revisions", GetRevisions(kind))
+	routes.GET("/:id/revisions/:rev", GetRevision(kind))
+	routes.GET("/:id/revisions/:rev/diff", GetRevisionDiff(kind))
+	routes.POST("/:id/revisions/:rev/restore", RequireScope(scope), RestoreRevision(kind))
+}
+
+// revisionKind returns the kind of the revisions of an entity of the audit log, or an empty string if it has none
+func revisionKind(entity string) string {
+	switch entity {
+	case "film":
+		return "film"
+	case "person", "actor", "director":
+		return "person"
+	}
+	return ""
+}
+
+func revisionCollection(kind string) *mongo.Collection {
+	if kind == "film" {
+		return filmColl
+	}
+	return personColl
+}
+
+// recordRevision saves the state of an entity after a change of the audit log, and its state before if it is the
+// first recorded change
+func recordRevision(entry AuditEntry, before bson.M, after bson.M) error {
+	kind := revisionKind(entry.Entity)
+	if len(kind) == 0 || after == nil || isTrashed(after) || (entry.Action == updateAction && len(entry.Changes) == 0) {
+		return nil
+	}
+
+	var roles []TrashedRole
+	if kind == "person" {
+		roles = findActorRoles(entry.EntityId)
+	}
+
+	// The roles of the initial snapshot are the current ones, the former ones being unknown
+	if before != nil && FindRevision(kind, entry.EntityId, 1).Id.IsZero() {
+		_, err := AddRevision(Revision{Kind: kind, EntityId: entry.EntityId, Time: entry.Time, Action: initialAction, Document: before, Roles: roles})
+		if err != nil {
+			return err
+		}
+	}
+
+	_, err := AddRevision(Revision{
+		Kind:      kind,
+		EntityId:  entry.EntityId,
+		Time:      entry.Time,
+		Actor:     entry.Actor,
+		RequestId: entry.RequestId,
+		Action:    entry.Action,
+		Document:  after,
+		Roles:     roles,
+	})
+	return err
+}
+
+// GetRevisions returns the revisions of an entity, without their documents
+func GetRevisions(kind string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if !primitive.IsValidObjectID(c.Param("id")) {
+			c.IndentedJSON(http.StatusBadRequest, gin.H{"message": "Id is invalid"})
+			return
+		}
+
+		c.IndentedJSON(http.StatusOK, FindRevisions(kind, c.Param("id")))
+	}
+}
+
+func GetRevision(kind string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		revision, ok := findRevisionParam(c, kind, c.Param("rev"))
+		if !ok {
+			return
+		}
+
+		c.IndentedJSON(http.StatusOK, revision)
+	}
+}
+
+// GetRevisionDiff returns the changes between a revision and the one given by ?with, or the current state by default
+func GetRevisionDiff(kind string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		revision, ok := findRevisionParam(c, kind, c.Param("rev"))
+		if !ok {
+			return
+		}
+
+		with := "current"
+		var other bson.M
+		if withStr, exists := c.GetQuery("with"); exists {
+			otherRevision, ok := findRevisionParam(c, kind, withStr)
+			if !ok {
+				return
+			}
+			with = withStr
+			other = otherRevision.Document
+		} else {
+			other = findDocument(revisionCollection(kind), c.Param("id"))
+		}
+
+		c.IndentedJSON(http.StatusOK, gin.H{"from": revision.Number, "to": with, "changes": diffDocuments(revision.Document, other)})
+	}
+}
+
+// RestoreRevision restores the state of an entity of a revision, and updates the other side of its links, like the
+// films of the actors of a film. The slug, the poster and the ratings computed from the reviews are kept, since
+// they are not edited directly.
+func RestoreRevision(kind string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		revision, ok := findRevisionParam(c, kind, c.Param("rev"))
+		if !ok {
+			return
+		}
+
+		current := findDocument(revisionCollection(kind), revision.EntityId)
+		if current == nil || isTrashed(current) {
+			c.IndentedJSON(http.StatusNotFound, gin.H{"message": "No item with the specified id"})
+			return
+		}
+
+		document := bson.M{}
+		for field, value := range revision.Document {
+			document[field] = value
+		}
+
+		if kind == "film" {
+			restoreFilm(c, current, document)
+		} else {
+			restorePerson(c, current, document, revision.Roles)
+		}
+	}
+}
+
+func restoreFilm(c *gin.Context, current bson.M, document bson.M) {
+	keepFields(document, current, "slug", "poster", "poster_variants", "user_rating_count", "user_rating_sum", "user_rating_average")
+
+	var film, restored Film
+	if err := decodeDocument(current, &film); err != nil {
+		c.IndentedJSON(http.StatusInternalServerError, gin.H{"message": err.Error()})
+		return
+	}
+	if err := decodeDocument(document, &restored); err != nil {
+		c.IndentedJSON(http.StatusInternalServerError, gin.H{"message": err.Error()})
+		return
+	}
+
+	var people, crew, studios []string
+	for _, role := range restored.Roles {
+		people = append(people, role.ActorId)
+	}
+	people = append(people, restored.Directors...)
+	for _, credit := range restored.Credits {
+		people = append(people, credit.PersonId)
+		if !isLegacyCredit(credit) && !containsString(crew, credit.PersonId) {
+			crew = append(crew, credit.PersonId)
+		}
+	}
+	for _, studio := range restored.Studios {
+		studios = append(studios, studio.StudioId)
+	}
+	if !areRestoredIdsValid(c, people, ArePeopleIdsValid) || !areRestoredIdsValid(c, restored.Genres, AreGenresIdsValid) || !areRestoredIdsValid(c, studios, AreStudiosIdsValid) {
+		return
+	}
+
+	id := film.Id.Hex()
+	if _, err := RestoreDocument(filmColl, id, document); err != nil {
+		c.IndentedJSON(http.StatusInternalServerError, gin.H{"message": err.Error()})
+		return
+	}
+
+	// The users rating is computed from the current reviews
+	var usersSource *RatingSource
+	if source, exists := film.Ratings[usersRating]; exists {
+		usersSource = &source
+	}
+	if _, err := SetFilmRating(id, usersRating, usersSource); err != nil {
+		c.IndentedJSON(http.StatusInternalServerError, gin.H{"message": err.Error()})
+		return
+	}
+
+	linkCreditedPeople(c, film, restored.Roles, restored.Directors, crew)
+
+	var oldStudios []string
+	for _, studio := range film.Studios {
+		oldStudios = append(oldStudios, studio.StudioId)
+	}
+	syncLinks(id, film.Genres, restored.Genres, AddFilmsToGenre, RemoveFilmsFromGenre)
+	syncLinks(id, oldStudios, studios, AddFilmsToStudio, RemoveFilmsFromStudio)
+
+	c.IndentedJSON(http.StatusOK, FindFilm(bson.M{"_id": film.Id}))
+}
+
+// restorePerson restores a person and the roles and directions of the films, the roles being the ones of the revision.
+// The crew films are kept, since a credit can't be added back without its job.
+func restorePerson(c *gin.Context, current bson.M, document bson.M, roles []TrashedRole) {
+	keepFields(document, current, "slug", "films")
+
+	var person, restored Person
+	if err := decodeDocument(current, &person); err != nil {
+		c.IndentedJSON(http.StatusInternalServerError, gin.H{"message": err.Error()})
+		return
+	}
+	if err := decodeDocument(document, &restored); err != nil {
+		c.IndentedJSON(http.StatusInternalServerError, gin.H{"message": err.Error()})
+		return
+	}
+
+	if !areRestoredIdsValid(c, append(append([]string{}, restored.ActedFilms...), restored.DirectedFilms...), AreFilmsIdsValid) {
+		return
+	}
+
+	id := person.Id.Hex()
+	if _, err := RestoreDocument(personColl, id, document); err != nil {
+		c.IndentedJSON(http.StatusInternalServerError, gin.H{"message": err.Error()})
+		return
+	}
+
+	addRoles := func(film string, people []string) (int64, error) {
+		return AddActorsToFilm(film, restoredRoles(people[0], film, roles))
+	}
+	syncLinks(id, person.ActedFilms, restored.ActedFilms, addRoles, RemoveActorsFromFilm)
+	syncLinks(id, person.DirectedFilms, restored.DirectedFilms, AddDirectorsToFilm, RemoveDirectorsFromFilm)
+
+	c.IndentedJSON(http.StatusOK, FindPersonById(person.Id))
+}
+
+// restoredRoles returns the roles of an actor on a film saved with a revision of the actor. The revisions made before
+// the roles were saved don't have them, so they are taken from the last revision of the film where the actor has a
+// role, the role being unnamed if there is none.
+func restoredRoles(actorId string, filmId string, roles []TrashedRole) []Role {
+	result := []Role{}
+	for _, role := range roles {
+		if role.FilmId == filmId {
+			result = append(result, role.Role)
+		}
+	}
+	if len(result) > 0 {
+		return result
+	}
+
+	var film Film
+	if revision := FindLastRevision(bson.M{"kind": "film", "entity_id": filmId, "document.roles.actor": actorId}); !revision.Id.IsZero() {
+		if err := decodeDocument(revision.Document, &film); err != nil {
+			panic(err)
+		}
+	}
+	for _, role := range film.Roles {
+		if role.ActorId == actorId {
+			result = append(result, role)
+		}
+	}
+	if len(result) == 0 {
+		result = append(result, Role{ActorId: actorId})
+	}
+	return result
+}
+
+// findRevisionParam returns the revision of the entity of the url with the given number. If there is none, it answers
+// with an error and returns false.
+func findRevisionParam(c *gin.Context, kind string, number string) (Revision, bool) {
+	rev, err := strconv.Atoi(number)
+	if err != nil || !primitive.IsValidObjectID(c.Param("id")) {
+		c.IndentedJSON(http.StatusBadRequest, gin.H{"message": "Id or revision is invalid"})
+		return Revision{}, false
+	}
+
+	revision := FindRevision(kind, c.Param("id"), rev)
+	if revision.Id.IsZero() {
+		c.IndentedJSON(http.StatusNotFound, gin.H{"message": "Revision not found"})
+		return Revision{}, false
+	}
+
+	return revision, true
+}
+
+// areRestoredIdsValid checks that the items linked by a revision still exist. If not, it answers with an error.
+func areRestoredIdsValid(c *gin.Context, ids []string, areValid func([]string) (bool, gin.H)) bool {
+	if len(ids) == 0 {
+		return true
+	}
+
+	if valid, msg := areValid(ids); !valid {
+		c.IndentedJSON(http.StatusConflict, msg)
+		return false
+	}
+	return true
+}
+
+// syncLinks adds the entity to the items it is now linked to, and removes it from the ones it is no longer linked to
+func syncLinks(entityId string, oldIds []string, newIds []string, add func(string, []string) (int64, error), remove func(string, []string) (int64, error)) {
+	for _, id := range difference(oldIds, newIds) {
+		if _, err := remove(id, []string{entityId}); err != nil {
+			panic(err)
+		}
+	}
+	for _, id := range difference(newIds, oldIds) {
+		if _, err := add(id, []string{entityId}); err != nil {
+			panic(err)
+		}
+	}
+}
+
+// keepFields sets the fields of the document to their current values
+func keepFields(document bson.M, current bson.M, fields ...string) {
+	for _, field := range fields {
+		if value, exists := current[field]; exists {
+			document[field] = value
+		} else {
+			delete(document, field)
+		}
+	}
+}
+
+func decodeDocument(document bson.M, v interface{}) error {
+	data, err := bson.Marshal(document)
+	if err != nil {
+		return err
+	}
+	return bson.Unmarshal(data, v)
+}
@@ -0,0 +1,114 @@
+package film_api
+
+import (
+	"context"
+	"filmflix/db_connection"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+var revisionColl *mongo.Collection
+
+func InitRevisionCollection(client *mongo.Client) {
+	revisionColl = db_connection.GetCollection(client, "films", "revisions")
+
+	// The numbers of the revisions of an entity are unique, even when two revisions are added at once
+	_, err := revisionColl.Indexes().CreateOne(context.TODO(), mongo.IndexModel{
+		Keys:    bson.D{{Key: "kind", Value: 1}, {Key: "entity_id", Value: 1}, {Key: "number", Value: 1}},
+		Options: options.Index().SetUnique(true),
+	})
+	if err != nil {
+		panic(err)
+	}
+}
+
+// FindRevisions retrieves the revisions of an entity without their documents, the most recent first
+func FindRevisions(kind string, entityId string) []Revision {
+	results := []Revision{}
+	cursor, err := revisionColl.Find(context.TODO(), bson.M{"kind": kind, "entity_id": entityId},
+		options.Find().SetSort(bson.M{"number": -1}).SetProjection(bson.M{"document": 0}))
+	if err != nil {
+		panic(err)
+	}
+
+	if err = cursor.All(context.TODO(), &results); err != nil {
+		panic(err)
+	}
+
+	return results
+}
+
+func FindRevision(kind string, entityId string, number int) Revision {
+	var revision Revision
+	err := revisionColl.FindOne(context.TODO(), bson.M{"kind": kind, "entity_id": entityId, "number": number}).Decode(&revision)
+
+	if err == mongo.ErrNoDocuments {
+		return revision
+	}
+	if err != nil {
+		panic(err)
+	}
+
+	return revision
+}
+
+// FindLastRevision retrieves the last revision of an entity matching the filter
+func FindLastRevision(filter bson.M) Revision {
+	var revision Revision
+	err := revisionColl.FindOne(context.TODO(), filter, options.FindOne().SetSort(bson.M{"number": -1})).Decode(&revision)
+
+	if err == mongo.ErrNoDocuments {
+		return revision
+	}
+	if err != nil {
+		panic(err)
+	}
+
+	return revision
+}
+
+// AddRevision saves a revision of an entity, numbered after the last one. If another revision takes the number first,
+// the revision is numbered again.
+func AddRevision(revision Revision) (Revision, error) {
+	for {
+		var last Revision
+		err := revisionColl.FindOne(context.TODO(), bson.M{"kind": revision.Kind, "entity_id": revision.EntityId},
+			options.FindOne().SetSort(bson.M{"number": -1}).SetProjection(bson.M{"number": 1})).Decode(&last)
+		if err != nil && err != mongo.ErrNoDocuments {
+			return Revision{}, err
+		}
+
+		revision.Id = primitive.NewObjectID()
+		revision.Number = last.Number + 1
+
+		_, err = revisionColl.InsertOne(context.TODO(), revision)
+		if mongo.IsDuplicateKeyError(err) {
+			continue
+		}
+		if err != nil {
+			return Revision{}, err
+		}
+
+		return revision, nil
+	}
+}
+
+// RestoreDocument replaces the document of an entity by the one of a revision
+func RestoreDocument(coll *mongo.Collection, idString string, document bson.M) (int64, error) {
+	id, err := primitive.ObjectIDFromHex(idString)
+	if err != nil {
+		return 0, err
+	}
+	document["_id"] = id
+
+	result, err := coll.ReplaceOne(context.TODO(), bson.M{"_id": id}, document)
+	if err != nil {
+		return 0, err
+	}
+
+	catalogueChanged(catalogueItemType(coll), idString)
+
+	return result.ModifiedCount, nil
+}
@@ -0,0 +1,43 @@
+package film_api
+
+import (
+	"github.com/gin-gonic/gin"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+func InitSearchApiRoutes(apiRoutes *gin.RouterGroup) {
+	apiRoutes.GET("/search", GetSearch)
+}
+
+// GetSearch returns the films, actors and directors whose title or name contains the "q" query param.
+// With fuzzy=true, the titles and names close to it are returned too, with the confidence of the match.
+func GetSearch(c *gin.Context) {
+	query := strings.TrimSpace(c.Query("q"))
+	if len(query) == 0 {
+		c.IndentedJSON(http.StatusBadRequest, gin.H{"message": "The q query param is required"})
+		return
+	}
+
+	limit := 20
+	if l := c.Query("limit"); len(l) > 0 {
+		limit, _ = strconv.Atoi(l)
+	}
+
+	if fuzzy, _ := strconv.ParseBool(c.Query("fuzzy")); !fuzzy {
+		c.IndentedJSON(http.StatusOK, search(query, parseItemTypes(c), limit))
+		return
+	}
+
+	minScore := fuzzyMinScore
+	if s := c.Query("min_score"); len(s) > 0 {
+		var err error
+		if minScore, err = strconv.ParseFloat(s, 64); err != nil {
+			c.IndentedJSON(http.StatusBadRequest, gin.H{"message": "min_score must be a number"})
+			return
+		}
+	}
+
+	c.IndentedJSON(http.StatusOK, fuzzySearch(query, parseItemTypes(c), minScore, limit))
+}
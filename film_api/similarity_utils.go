@@ -0,0 +1,199 @@
+package film_api
+
+import (
+	"go.mongodb.org/mongo-driver/bson"
+	"math"
+	"sort"
+	"strings"
+	"sync"
+	"unicode"
+)
+
+// Weights of each criterion in the similarity score of two films, their sum is 1
+const (
+	directorsWeight   = 0.3
+	castWeight        = 0.25
+	releaseYearWeight = 0.1
+	descriptionWeight = 0.35
+)
+
+// releaseYearScale is the gap in years for which the release year proximity is halved
+const releaseYearScale = 5.0
+
+// descriptionStopWords are the words ignored when comparing descriptions
+var descriptionStopWords = map[string]struct{}{
+	"a": {}, "an": {}, "and": {}, "are": {}, "as": {}, "at": {}, "be": {}, "but": {}, "by": {}, "for": {}, "from": {},
+	"has": {}, "he": {}, "her": {}, "his": {}, "in": {}, "into": {}, "is": {}, "it": {}, "its": {}, "of": {}, "on": {},
+	"or": {}, "she": {}, "that": {}, "the": {}, "their": {}, "they": {}, "this": {}, "to": {}, "was": {}, "who": {},
+	"with": {},
+}
+
+type filmFeatures struct {
+	directors   map[string]struct{}
+	actors      map[string]struct{}
+	year        int                // year is 0 when the release date could not be parsed
+	description map[string]float64 // description is the normalized TF-IDF vector of the description
+}
+
+// similarityIndex holds the features of every film, built in memory from the films collection and rebuilt lazily after each write
+type similarityIndex struct {
+	mutex    sync.Mutex
+	dirty    bool
+	features map[string]*filmFeatures
+}
+
+var filmSimilarityIndex = &similarityIndex{dirty: true}
+
+type SimilarFilm struct {
+	Film  Film    `json:"film"`
+	Score float64 `json:"score"`
+}
+
+func (index *similarityIndex) invalidate() {
+	index.mutex.Lock()
+	index.dirty = true
+	index.mutex.Unlock()
+}
+
+// load returns the features of every film, rebuilding them first if the films collection changed since the last build
+func (index *similarityIndex) load() map[string]*filmFeatures {
+	index.mutex.Lock()
+	defer index.mutex.Unlock()
+
+	if !index.dirty {
+		return index.features
+	}
+
+	films := FindFilms(bson.M{}, 0)
+	features := make(map[string]*filmFeatures, len(films))
+	termsFrequencies := make(map[string]map[string]float64, len(films))
+	documentsFrequencies := make(map[string]int)
+
+	for _, film := range films {
+		filmId := film.Id.Hex()
+		feature := &filmFeatures{
+			directors: make(map[string]struct{}),
+			actors:    make(map[string]struct{}),
+			year:      film.Release.Year(),
+		}
+		for _, director := range film.Directors {
+			feature.directors[director] = struct{}{}
+		}
+		for _, role := range film.Roles {
+			feature.actors[role.ActorId] = struct{}{}
+		}
+		features[filmId] = feature
+
+		frequencies := make(map[string]float64)
+		for _, term := range tokenizeDescription(film.Description) {
+			frequencies[term]++
+		}
+		for term := range frequencies {
+			documentsFrequencies[term]++
+		}
+		termsFrequencies[filmId] = frequencies
+	}
+
+	for filmId, frequencies := range termsFrequencies {
+		vector := make(map[string]float64, len(frequencies))
+		norm := 0.0
+		for term, frequency := range frequencies {
+			weight := frequency * math.Log(float64(len(films))/float64(documentsFrequencies[term]))
+			vector[term] = weight
+			norm += weight * weight
+		}
+		norm = math.Sqrt(norm)
+		for term := range vector {
+			if norm > 0 {
+				vector[term] /= norm
+			}
+		}
+		features[filmId].description = vector
+	}
+
+	index.features = features
+	index.dirty = false
+
+	return features
+}
+
+// similarFilms returns the ids of the films similar to the given one with their score, the most similar first
+func (index *similarityIndex) similarFilms(filmId string) ([]string, map[string]float64) {
+	features := index.load()
+	reference, found := features[filmId]
+	if !found {
+		return nil, nil
+	}
+
+	scores := make(map[string]float64)
+	var ids []string
+	for otherId, other := range features {
+		if otherId == filmId {
+			continue
+		}
+		score := filmsSimilarity(reference, other)
+		if score <= 0 {
+			continue
+		}
+		scores[otherId] = score
+		ids = append(ids, otherId)
+	}
+
+	sort.Slice(ids, func(i, j int) bool {
+		if scores[ids[i]] == scores[ids[j]] {
+			return ids[i] < ids[j]
+		}
+		return scores[ids[i]] > scores[ids[j]]
+	})
+
+	return ids, scores
+}
+
+// filmsSimilarity returns a score between 0 and 1 telling how similar two films are
+func filmsSimilarity(a, b *filmFeatures) float64 {
+	score := directorsWeight*jaccardIndex(a.directors, b.directors) + castWeight*jaccardIndex(a.actors, b.actors)
+
+	if a.year != 0 && b.year != 0 {
+		gap := math.Abs(float64(a.year - b.year))
+		score += releaseYearWeight * releaseYearScale / (releaseYearScale + gap)
+	}
+
+	// Both vectors are normalized, so their dot product is their cosine similarity
+	cosine := 0.0
+	for term, weight := range a.description {
+		cosine += weight * b.description[term]
+	}
+
+	return score + descriptionWeight*cosine
+}
+
+func jaccardIndex(a, b map[string]struct{}) float64 {
+	if len(a) == 0 || len(b) == 0 {
+		return 0
+	}
+
+	intersection := 0
+	for x := range a {
+		if _, found := b[x]; found {
+			intersection++
+		}
+	}
+
+	return float64(intersection) / float64(len(a)+len(b)-intersection)
+}
+
+func tokenizeDescription(description string) []string {
+	words := strings.FieldsFunc(strings.ToLower(description), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsNumber(r)
+	})
+
+	terms := words[:0]
+	for _, word := range words {
+		if _, found := descriptionStopWords[word]; found || len([]rune(word)) < 2 {
+			continue
+		}
+		terms = append(terms, word)
+	}
+
+	return terms
+}
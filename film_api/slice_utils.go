@@ -13,3 +13,26 @@ func difference(a, b []string) []string {
 	}
 	return diff
 }
+
+func intersection(a, b []string) []string {
+	mb := make(map[string]struct{}, len(b))
+	for _, x := range b {
+		mb[x] = struct{}{}
+	}
+	var common []string
+	for _, x := range a {
+		if _, found := mb[x]; found {
+			common = append(common, x)
+		}
+	}
+	return common
+}
+
+func containsString(a []string, x string) bool {
+	for _, y := range a {
+		if y == x {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,173 @@
+package film_api
+
+import (
+	"context"
+	"filmflix/db_connection"
+	"fmt"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"regexp"
+	"time"
+)
+
+// SlugHistoryEntry records a slug that an item had before being renamed or merged into another one
+type SlugHistoryEntry struct {
+	Id        string    `json:"-" bson:"_id"` // Id is the kind and the slug joined by a slash, so that an old slug is unique per kind
+	Kind      string    `json:"kind" bson:"kind"`
+	Slug      string    `json:"slug" bson:"slug"`
+	Target    string    `json:"target" bson:"target"` // Target is the id of the item the slug now resolves to
+	CreatedAt time.Time `json:"created_at" bson:"created_at"`
+}
+
+var slugHistoryColl *mongo.Collection
+
+func InitSlugHistoryCollection(client *mongo.Client) {
+	slugHistoryColl = db_connection.GetCollection(client, "films", "slug_history")
+}
+
+// slugKind returns the kind whose namespace holds the slugs of the given kind, the actors and directors being all people
+func slugKind(kind string) string {
+	if kind == "actor" || kind == "director" {
+		return "person"
+	}
+	return kind
+}
+
+// slugCollection returns the collection of the items of the given kind
+func slugCollection(kind string) *mongo.Collection {
+	switch kind {
+	case "film":
+		return filmColl
+	case "actor":
+		return actorColl
+	case "director":
+		return directorColl
+	case "genre":
+		return genreColl
+	case "studio":
+		return studioColl
+	case "person":
+		return personColl
+	}
+	panic(fmt.Sprintf("no collection for the kind %v", kind))
+}
+
+// FindIdBySlug returns the id of the item of the given kind currently having the given slug, or a zero id if there is none
+func FindIdBySlug(kind string, slug string) primitive.ObjectID {
+	var item struct {
+		Id primitive.ObjectID `bson:"_id"`
+	}
+	err := slugCollection(kind).FindOne(context.TODO(), bson.M{"slug": slug}, options.FindOne().SetProjection(bson.M{"_id": 1})).Decode(&item)
+
+	if err == mongo.ErrNoDocuments {
+		return primitive.NilObjectID
+	}
+	if err != nil {
+		panic(err)
+	}
+
+	return item.Id
+}
+
+// FindSlugHistoryTarget returns the id of the item an old slug now resolves to, or an empty string if there is none
+func FindSlugHistoryTarget(kind string, slug string) string {
+	var entry SlugHistoryEntry
+	err := slugHistoryColl.FindOne(context.TODO(), bson.M{"_id": slugKind(kind) + "/" + slug}).Decode(&entry)
+
+	if err == mongo.ErrNoDocuments {
+		return ""
+	}
+	if err != nil {
+		panic(err)
+	}
+
+	return entry.Target
+}
+
+// AddSlugHistory makes an old slug resolve to the item with the given id
+func AddSlugHistory(kind string, slug string, target string) error {
+	kind = slugKind(kind)
+	entry := SlugHistoryEntry{Id: kind + "/" + slug, Kind: kind, Slug: slug, Target: target, CreatedAt: time.Now()}
+	_, err := slugHistoryColl.ReplaceOne(context.TODO(), bson.M{"_id": entry.Id}, entry, options.Replace().SetUpsert(true))
+
+	return err
+}
+
+// UniqueSlug returns the given base slug, followed by the first free number if it is already taken by another item of
+// the same kind. The slug of an item whose name can't be transliterated, like a name in kanji, is its id.
+func UniqueSlug(kind string, base string, id primitive.ObjectID) string {
+	if len(base) == 0 {
+		return id.Hex()
+	}
+
+	taken := takenSlugs(kind, base, id)
+	slug := base
+	for i := 2; taken[slug]; i++ {
+		slug = fmt.Sprintf("%v-%v", base, i)
+	}
+
+	return slug
+}
+
+// takenSlugs returns the slugs made of the base, followed or not by a number, which are used or were used by the items
+// of the given kind other than the given one
+func takenSlugs(kind string, base string, id primitive.ObjectID) map[string]bool {
+	pattern := primitive.Regex{Pattern: "^" + regexp.QuoteMeta(base) + "(-[0-9]+)?$"}
+
+	var items []struct {
+		Slug string `bson:"slug"`
+	}
+	cursor, err := slugCollection(kind).Find(context.TODO(), bson.M{"slug": pattern, "_id": bson.M{"$ne": id}}, options.Find().SetProjection(bson.M{"slug": 1}))
+	if err != nil {
+		panic(err)
+	}
+	if err = cursor.All(context.TODO(), &items); err != nil {
+		panic(err)
+	}
+
+	var entries []SlugHistoryEntry
+	cursor, err = slugHistoryColl.Find(context.TODO(), bson.M{"kind": slugKind(kind), "slug": pattern, "target": bson.M{"$ne": id.Hex()}})
+	if err != nil {
+		panic(err)
+	}
+	if err = cursor.All(context.TODO(), &entries); err != nil {
+		panic(err)
+	}
+
+	taken := make(map[string]bool, len(items)+len(entries))
+	for _, item := range items {
+		taken[item.Slug] = true
+	}
+	for _, entry := range entries {
+		taken[entry.Slug] = true
+	}
+	return taken
+}
+
+// createSlugIndex makes the slugs of the items of a collection unique, the items created before the slugs having none
+func createSlugIndex(coll *mongo.Collection) {
+	_, err := coll.Indexes().CreateOne(context.TODO(), mongo.IndexModel{
+		Keys:    bson.D{{Key: "slug", Value: 1}},
+		Options: options.Index().SetUnique(true).SetSparse(true),
+	})
+	if err != nil {
+		panic(err)
+	}
+}
+
+// BackfillSlugs gives a slug to the films, actors and directors created before slugs existed
+func BackfillSlugs() {
+	for _, film := range FindFilms(bson.M{"slug": bson.M{"$exists": false}}, 0) {
+		if _, err := UpdateFilmById(film.Id.Hex(), bson.M{"slug": UniqueSlug("film", filmSlugBase(film), film.Id)}); err != nil {
+			panic(err)
+		}
+	}
+	for _, actor := range FindActors(bson.M{"slug": bson.M{"$exists": false}}, 0) {
+		UpdateActorById(actor.Id.Hex(), bson.M{"slug": UniqueSlug("actor", slugify(actor.Name), actor.Id)})
+	}
+	for _, director := range FindDirectors(bson.M{"slug": bson.M{"$exists": false}}, 0) {
+		UpdateDirectorById(director.Id.Hex(), bson.M{"slug": UniqueSlug("director", slugify(director.Name), director.Id)})
+	}
+}
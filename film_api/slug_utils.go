@@ -0,0 +1,127 @@
+package film_api
+
+import (
+	"fmt"
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"net/http"
+	"regexp"
+	"strings"
+)
+
+var (
+	slugSeparatorsRegexp = regexp.MustCompile(`[^a-z0-9]+`)
+	slugSuffixRegexp     = regexp.MustCompile(`-\d+$`)
+)
+
+// slugify turns a text into a lowercase ascii slug made of words separated by dashes
+func slugify(text string) string {
+	return strings.Trim(slugSeparatorsRegexp.ReplaceAllString(foldText(transliterate(text)), "-"), "-")
+}
+
+// filmSlugBase returns the slug of a film before any collision suffix, made of its title and its release year.
+// The original title is used when the title has no latin characters, and the base is empty if neither has.
+func filmSlugBase(film Film) string {
+	slug := slugify(film.Title)
+	if len(slug) == 0 {
+		slug = slugify(film.OriginalTitle)
+	}
+
+	if year := film.Release.Year(); year != 0 && len(slug) > 0 {
+		slug = fmt.Sprintf("%v-%v", slug, year)
+	}
+
+	return slug
+}
+
+// renameSlug returns the slug of an item whose name changed, keeping the current slug if it was built from the same base.
+// When the slug changes, the current one is kept in the history so that it still resolves to the item.
+func renameSlug(kind string, id primitive.ObjectID, currentSlug string, newBase string) (string, error) {
+	// The slug of an item whose name can't be transliterated is its id
+	if len(newBase) == 0 {
+		newBase = id.Hex()
+	}
+
+	if len(currentSlug) > 0 && (currentSlug == newBase || slugSuffixRegexp.ReplaceAllString(currentSlug, "") == newBase) {
+		return currentSlug, nil
+	}
+
+	if len(currentSlug) > 0 {
+		if err := AddSlugHistory(kind, currentSlug, id.Hex()); err != nil {
+			return "", err
+		}
+	}
+
+	return UniqueSlug(kind, newBase, id), nil
+}
+
+// ResolveSlug is a middleware replacing the slug given as ":id" param by the id of the item of the given kind it identifies.
+// The old slugs of renamed items are permanently redirected to their current slug for GET requests.
+func ResolveSlug(kind string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		slug := c.Param("id")
+		if len(slug) == 0 || primitive.IsValidObjectID(slug) {
+			c.Next()
+			return
+		}
+
+		if id := FindIdBySlug(kind, slug); !id.IsZero() {
+			setParam(c, "id", id.Hex())
+			c.Next()
+			return
+		}
+
+		target := FindSlugHistoryTarget(kind, slug)
+		targetId, err := primitive.ObjectIDFromHex(target)
+		if err != nil {
+			c.AbortWithStatusJSON(http.StatusNotFound, gin.H{"message": "No item with the specified slug"})
+			return
+		}
+
+		if c.Request.Method == http.MethodGet {
+			newSlug := findSlugById(kind, targetId)
+			if len(newSlug) == 0 {
+				newSlug = target
+			}
+			location := strings.Replace(c.Request.URL.Path, "/"+slug, "/"+newSlug, 1)
+			if len(c.Request.URL.RawQuery) > 0 {
+				location += "?" + c.Request.URL.RawQuery
+			}
+			c.Redirect(http.StatusMovedPermanently, location)
+			c.Abort()
+			return
+		}
+
+		setParam(c, "id", target)
+		c.Next()
+	}
+}
+
+// findSlugById returns the current slug of the item of the given kind with the given id
+func findSlugById(kind string, id primitive.ObjectID) string {
+	switch kind {
+	case "film":
+		return FindFilm(bson.M{"_id": id}).Slug
+	case "actor":
+		return FindActor(bson.M{"_id": id}).Slug
+	case "director":
+		return FindDirector(bson.M{"_id": id}).Slug
+	case "genre":
+		return FindGenre(bson.M{"_id": id}).Slug
+	case "studio":
+		return FindStudio(bson.M{"_id": id}).Slug
+	case "person":
+		return FindPerson(bson.M{"_id": id}).Slug
+	}
+	return ""
+}
+
+func setParam(c *gin.Context, key string, value string) {
+	for i, param := range c.Params {
+		if param.Key == key {
+			c.Params[i].Value = value
+			return
+		}
+	}
+}
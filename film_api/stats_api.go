@@ -0,0 +1,68 @@
+package film_api
+
+import (
+	"github.com/gin-gonic/gin"
+	"net/http"
+	"os"
+	"sync"
+	"time"
+)
+
+// defaultStatsCacheTTL is used when the STATS_CACHE_TTL env var is not a valid duration
+const defaultStatsCacheTTL = 10 * time.Minute
+
+type CatalogueStats struct {
+	FilmsPerYear            []YearCount      `json:"films_per_year"`
+	FilmsPerDecade          []YearCount      `json:"films_per_decade"`
+	AverageScorePerDirector []DirectorRating `json:"average_rt_score_per_director"`
+	MostProlificActors      []ActorFilmCount `json:"most_prolific_actors"`
+	LongestDirectorCareers  []DirectorCareer `json:"longest_director_careers"`
+	Totals                  CatalogueTotals  `json:"totals"`
+	ComputedAt              time.Time        `json:"computed_at"`
+}
+
+// statsCache keeps the last computed statistics until they expire or the catalogue changes
+type statsCache struct {
+	mutex sync.Mutex
+	stats *CatalogueStats
+}
+
+var catalogueStatsCache = &statsCache{}
+
+func (cache *statsCache) invalidate() {
+	cache.mutex.Lock()
+	cache.stats = nil
+	cache.mutex.Unlock()
+}
+
+func (cache *statsCache) load() *CatalogueStats {
+	cache.mutex.Lock()
+	defer cache.mutex.Unlock()
+
+	ttl, err := time.ParseDuration(os.Getenv("STATS_CACHE_TTL"))
+	if err != nil {
+		ttl = defaultStatsCacheTTL
+	}
+
+	if cache.stats == nil || time.Since(cache.stats.ComputedAt) > ttl {
+		cache.stats = &CatalogueStats{
+			FilmsPerYear:            FindFilmsPerYear(),
+			FilmsPerDecade:          FindFilmsPerDecade(),
+			AverageScorePerDirector: FindAverageScorePerDirector(),
+			MostProlificActors:      FindMostProlificActors(),
+			LongestDirectorCareers:  FindLongestDirectorCareers(),
+			Totals:                  CountCatalogueTotals(),
+			ComputedAt:              time.Now(),
+		}
+	}
+
+	return cache.stats
+}
+
+func InitStatsApiRoutes(apiRoutes *gin.RouterGroup) {
+	apiRoutes.GET("/stats", GetStats)
+}
+
+func GetStats(c *gin.Context) {
+	c.IndentedJSON(http.StatusOK, catalogueStatsCache.load())
+}
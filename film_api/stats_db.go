@@ -0,0 +1,166 @@
+package film_api
+
+import (
+	"context"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// statsRankingSize is the number of items kept in the rankings of the statistics
+const statsRankingSize = 10
+
+type YearCount struct {
+	Year  int `json:"year" bson:"_id"`
+	Count int `json:"count" bson:"count"`
+}
+
+type DirectorRating struct {
+	DirectorId   string  `json:"director" bson:"_id"`
+	Name         string  `json:"name" bson:"name"`
+	AverageScore float64 `json:"average_rt_score" bson:"average_score"`
+	RatedFilms   int     `json:"rated_films" bson:"rated_films"`
+}
+
+type ActorFilmCount struct {
+	ActorId string `json:"actor" bson:"_id"`
+	Name    string `json:"name" bson:"name"`
+	Films   int    `json:"films" bson:"films"`
+}
+
+type DirectorCareer struct {
+	DirectorId string `json:"director" bson:"_id"`
+	Name       string `json:"name" bson:"name"`
+	FirstYear  int    `json:"first_year" bson:"first_year"`
+	LastYear   int    `json:"last_year" bson:"last_year"`
+	Span       int    `json:"span" bson:"span"`
+}
+
+type CatalogueTotals struct {
+	Films      int64 `json:"films"`
+	Actors     int64 `json:"actors"`
+	Directors  int64 `json:"directors"`
+	RatedFilms int64 `json:"rated_films"`
+}
+
+// releaseYearStage adds to each film a "year" field holding the year of its parsed release date, or null if it has none
+var releaseYearStage = bson.M{"$addFields": bson.M{"year": bson.M{"$cond": bson.M{
+	"if":   bson.M{"$eq": []interface{}{bson.M{"$type": "$release.date"}, "date"}},
+	"then": bson.M{"$year": "$release.date"},
+	"else": nil,
+}}}}
+
+// rtScoreExpression is the critics score of a film, between 0 and 100, or null if it has none
+var rtScoreExpression = bson.M{"$ifNull": []interface{}{"$ratings." + criticsRating + ".normalized", nil}}
+
+// lookupNameStages adds to each item the name of the document of the given collection whose id is the "_id" of the item
+func lookupNameStages(collection string) []bson.M {
+	return []bson.M{
+		{"$lookup": bson.M{
+			"from":     collection,
+			"let":      bson.M{"itemId": bson.M{"$convert": bson.M{"input": "$_id", "to": "objectId", "onError": nil}}},
+			"pipeline": []bson.M{{"$match": bson.M{"$expr": bson.M{"$eq": []string{"$_id", "$$itemId"}}}}, {"$project": bson.M{"name": 1}}},
+			"as":       "item",
+		}},
+		{"$addFields": bson.M{"name": bson.M{"$ifNull": []interface{}{bson.M{"$arrayElemAt": []interface{}{"$item.name", 0}}, ""}}}},
+		{"$project": bson.M{"item": 0}},
+	}
+}
+
+func aggregate(collection *mongo.Collection, pipeline []bson.M, results interface{}) {
+	// The items in the trash are left out of all the aggregations
+	pipeline = append([]bson.M{{"$match": notDeleted(bson.M{})}}, pipeline...)
+	cursor, err := collection.Aggregate(context.TODO(), pipeline)
+	if err != nil {
+		panic(err)
+	}
+
+	if err = cursor.All(context.TODO(), results); err != nil {
+		panic(err)
+	}
+}
+
+func FindFilmsPerYear() []YearCount {
+	results := []YearCount{}
+	aggregate(filmColl, []bson.M{
+		releaseYearStage,
+		{"$match": bson.M{"year": bson.M{"$ne": nil}}},
+		{"$group": bson.M{"_id": "$year", "count": bson.M{"$sum": 1}}},
+		{"$sort": bson.M{"_id": 1}},
+	}, &results)
+
+	return results
+}
+
+func FindFilmsPerDecade() []YearCount {
+	results := []YearCount{}
+	aggregate(filmColl, []bson.M{
+		releaseYearStage,
+		{"$match": bson.M{"year": bson.M{"$ne": nil}}},
+		{"$group": bson.M{"_id": bson.M{"$subtract": []interface{}{"$year", bson.M{"$mod": []interface{}{"$year", 10}}}}, "count": bson.M{"$sum": 1}}},
+		{"$sort": bson.M{"_id": 1}},
+	}, &results)
+
+	return results
+}
+
+func FindAverageScorePerDirector() []DirectorRating {
+	results := []DirectorRating{}
+	pipeline := []bson.M{
+		{"$addFields": bson.M{"score": rtScoreExpression}},
+		{"$match": bson.M{"score": bson.M{"$ne": nil}}},
+		{"$unwind": "$directors"},
+		{"$group": bson.M{"_id": "$directors", "average_score": bson.M{"$avg": "$score"}, "rated_films": bson.M{"$sum": 1}}},
+		{"$sort": bson.D{{Key: "average_score", Value: -1}, {Key: "_id", Value: 1}}},
+		{"$limit": statsRankingSize},
+	}
+	aggregate(filmColl, append(pipeline, lookupNameStages(directorColl.Name())...), &results)
+
+	return results
+}
+
+func FindMostProlificActors() []ActorFilmCount {
+	results := []ActorFilmCount{}
+	pipeline := []bson.M{
+		{"$unwind": "$roles"},
+		{"$group": bson.M{"_id": "$roles.actor", "films": bson.M{"$addToSet": "$_id"}}},
+		{"$project": bson.M{"films": bson.M{"$size": "$films"}}},
+		{"$sort": bson.D{{Key: "films", Value: -1}, {Key: "_id", Value: 1}}},
+		{"$limit": statsRankingSize},
+	}
+	aggregate(filmColl, append(pipeline, lookupNameStages(actorColl.Name())...), &results)
+
+	return results
+}
+
+func FindLongestDirectorCareers() []DirectorCareer {
+	results := []DirectorCareer{}
+	pipeline := []bson.M{
+		releaseYearStage,
+		{"$match": bson.M{"year": bson.M{"$ne": nil}}},
+		{"$unwind": "$directors"},
+		{"$group": bson.M{"_id": "$directors", "first_year": bson.M{"$min": "$year"}, "last_year": bson.M{"$max": "$year"}}},
+		{"$addFields": bson.M{"span": bson.M{"$subtract": []string{"$last_year", "$first_year"}}}},
+		{"$sort": bson.D{{Key: "span", Value: -1}, {Key: "_id", Value: 1}}},
+		{"$limit": statsRankingSize},
+	}
+	aggregate(filmColl, append(pipeline, lookupNameStages(directorColl.Name())...), &results)
+
+	return results
+}
+
+func CountCatalogueTotals() CatalogueTotals {
+	count := func(collection *mongo.Collection, filter interface{}) int64 {
+		result, err := collection.CountDocuments(context.TODO(), filter)
+		if err != nil {
+			panic(err)
+		}
+		return result
+	}
+
+	return CatalogueTotals{
+		Films:      count(filmColl, notDeleted(bson.M{})),
+		Actors:     count(actorColl, notDeleted(bson.M{"facets": actorFacet})),
+		Directors:  count(directorColl, notDeleted(bson.M{"facets": directorFacet})),
+		RatedFilms: count(filmColl, notDeleted(bson.M{"ratings." + criticsRating: bson.M{"$exists": true}})),
+	}
+}
@@ -0,0 +1,229 @@
+package film_api
+
+import (
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"net/http"
+	"strconv"
+)
+
+// The roles a studio can have in a film
+const (
+	StudioRoleProduction   = "production"
+	StudioRoleDistribution = "distribution"
+)
+
+type Studio struct {
+	Id      primitive.ObjectID `json:"id" bson:"_id,omitempty"`
+	Slug    string             `json:"slug,omitempty" bson:"slug,omitempty"`
+	Name    string             `json:"name" bson:"name,omitempty"`
+	Country string             `json:"country" bson:"country,omitempty"`
+	Films   []string           `json:"films" bson:"films"` // Films is the slice of the ids of the films the studio worked on, whatever its role
+}
+
+// StudioCredit links a film to a studio which worked on it
+type StudioCredit struct {
+	StudioId string `json:"studio" bson:"studio"`
+	Role     string `json:"role" bson:"role"` // Role is either "production" or "distribution"
+}
+
+type RoleCount struct {
+	Role  string `json:"role" bson:"_id"`
+	Count int    `json:"count" bson:"count"`
+}
+
+type DirectorFilmCount struct {
+	DirectorId string `json:"director" bson:"_id"`
+	Name       string `json:"name" bson:"name"`
+	Films      int    `json:"films" bson:"films"`
+}
+
+type StudioStats struct {
+	Films        int                 `json:"films" bson:"films"`
+	FilmsPerRole []RoleCount         `json:"films_per_role" bson:"-"`
+	AverageScore float64             `json:"average_rt_score" bson:"average_score"`
+	FirstYear    int                 `json:"first_year" bson:"first_year"`
+	LastYear     int                 `json:"last_year" bson:"last_year"`
+	TopDirectors []DirectorFilmCount `json:"top_directors" bson:"-"`
+}
+
+func InitStudioApiRoutes(apiRoutes *gin.RouterGroup, client *mongo.Client) {
+	InitStudioCollection(client)
+	InitSlugHistoryCollection(client)
+	InitAuditCollection(client)
+
+	studioRoutes := apiRoutes.Group("/studios")
+	studioRoutes.Use(ResolveSlug("studio"))
+	studioRoutes.Use(Audit("studio", studioColl))
+	studioRoutes.GET("/", GetStudios)
+	studioRoutes.GET("/:id", GetStudioById)
+	studioRoutes.GET("/:id/films", GetStudioFilms)
+	studioRoutes.GET("/:id/stats", GetStudioStats)
+	studioRoutes.POST("/", RequireScope(writeFilmsScope), PostStudio)
+	studioRoutes.PATCH("/:id", RequireScope(writeFilmsScope), UpdateStudio)
+	studioRoutes.DELETE("/:id", RequireScope(writeFilmsScope), DeleteStudio)
+}
+
+func GetStudios(c *gin.Context) {
+	limit := 20
+	if l := c.Query("limit"); len(l) > 0 {
+		limit, _ = strconv.Atoi(l)
+	}
+
+	studios := FindStudios(bson.M{}, limit)
+	c.IndentedJSON(http.StatusOK, studios)
+}
+
+func GetStudioById(c *gin.Context) {
+	id, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		c.IndentedJSON(http.StatusBadRequest, gin.H{"message": "Id is invalid"})
+		return
+	}
+
+	studio := FindStudio(bson.M{"_id": id})
+	if len(studio.Name) > 0 {
+		c.IndentedJSON(http.StatusOK, studio)
+	} else {
+		c.IndentedJSON(http.StatusNotFound, gin.H{"message": "Studio not found"})
+	}
+}
+
+// GetStudioFilms returns the filmography of a studio, optionally restricted to a role with the "role" query param
+func GetStudioFilms(c *gin.Context) {
+	if !primitive.IsValidObjectID(c.Param("id")) {
+		c.IndentedJSON(http.StatusBadRequest, gin.H{"message": "Id is invalid"})
+		return
+	}
+
+	credit := bson.M{"studio": c.Param("id")}
+	if role := c.Query("role"); len(role) > 0 {
+		credit["role"] = role
+	}
+
+	films := FindFilms(bson.M{"studios": bson.M{"$elemMatch": credit}}, 0)
+	localizeFilms(c, films)
+	c.IndentedJSON(http.StatusOK, films)
+}
+
+func GetStudioStats(c *gin.Context) {
+	id, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		c.IndentedJSON(http.StatusBadRequest, gin.H{"message": "Id is invalid"})
+		return
+	}
+
+	if studio := FindStudio(bson.M{"_id": id}); len(studio.Name) == 0 {
+		c.IndentedJSON(http.StatusNotFound, gin.H{"message": "Studio not found"})
+		return
+	}
+
+	c.IndentedJSON(http.StatusOK, FindStudioStats(id.Hex()))
+}
+
+func PostStudio(c *gin.Context) {
+	var newStudio Studio
+	if err := c.BindJSON(&newStudio); err != nil {
+		return
+	}
+
+	if len(newStudio.Name) == 0 {
+		c.IndentedJSON(http.StatusBadRequest, gin.H{"message": "The name is required"})
+		return
+	}
+
+	// The films of a studio are set from the films, since each link has a role
+	newStudio.Films = []string{}
+
+	newStudio, err := AddStudio(newStudio)
+	if err != nil {
+		c.IndentedJSON(http.StatusBadRequest, gin.H{"message": err.Error()})
+		return
+	}
+
+	c.IndentedJSON(http.StatusCreated, newStudio)
+}
+
+// UpdateStudio is used to update all the fields of a studio EXCEPT its films (use PATCH /api/films/<id>/studios instead)
+func UpdateStudio(c *gin.Context) {
+	var updateData Studio
+	idString := c.Param("id")
+
+	id, err := primitive.ObjectIDFromHex(idString)
+	if err != nil {
+		c.IndentedJSON(http.StatusBadRequest, gin.H{"message": err.Error()})
+		return
+	}
+
+	oldStudio := FindStudio(bson.M{"_id": id})
+
+	if err := c.BindJSON(&updateData); err != nil {
+		c.IndentedJSON(http.StatusBadRequest, gin.H{"message": err.Error()})
+		return
+	}
+
+	updateData.Films = oldStudio.Films
+	updateData.Slug = ""
+	if len(updateData.Name) > 0 && updateData.Name != oldStudio.Name {
+		if updateData.Slug, err = renameSlug("studio", id, oldStudio.Slug, slugify(updateData.Name)); err != nil {
+			c.IndentedJSON(http.StatusInternalServerError, gin.H{"message": err.Error()})
+			return
+		}
+	}
+
+	result := UpdateStudioById(idString, updateData)
+
+	c.IndentedJSON(http.StatusNoContent, result)
+}
+
+func DeleteStudio(c *gin.Context) {
+	id, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		c.IndentedJSON(http.StatusBadRequest, gin.H{"message": err.Error()})
+		return
+	}
+
+	oldStudio := FindStudio(bson.M{"_id": id})
+
+	result := DeleteItemById(studioColl, id.Hex())
+
+	if result == 0 {
+		c.IndentedJSON(http.StatusNotModified, gin.H{"message": "No studio with the specified id"})
+		return
+	}
+
+	for _, film := range oldStudio.Films {
+		_, err := RemoveStudiosFromFilm(film, []string{id.Hex()})
+		if err != nil {
+			return
+		}
+	}
+
+	c.IndentedJSON(http.StatusNoContent, result)
+}
+
+// AreStudioCreditsValid checks that the studios of the credits exist and that their roles are known
+func AreStudioCreditsValid(credits []StudioCredit) (bool, gin.H) {
+	studios := make([]string, len(credits))
+	for i, credit := range credits {
+		if credit.Role != StudioRoleProduction && credit.Role != StudioRoleDistribution {
+			return false, gin.H{"message": "The role of a studio must be either production or distribution"}
+		}
+		studios[i] = credit.StudioId
+	}
+
+	return AreStudiosIdsValid(studios)
+}
+
+// creditedStudios returns the ids of the studios of the credits, without duplicates
+func creditedStudios(credits []StudioCredit) []string {
+	var studios []string
+	for _, credit := range credits {
+		if !containsString(studios, credit.StudioId) {
+			studios = append(studios, credit.StudioId)
+		}
+	}
+	return studios
+}
@@ -0,0 +1,178 @@
+package film_api
+
+import (
+	"context"
+	"filmflix/db_connection"
+	"fmt"
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+var studioColl *mongo.Collection
+
+func InitStudioCollection(client *mongo.Client) {
+	studioColl = db_connection.GetCollection(client, "films", "studios")
+	createSlugIndex(studioColl)
+}
+
+func FindStudios(filter bson.M, maxCount int) []Studio {
+	var results []Studio
+	limit := int64(maxCount)
+	cursor, err := studioColl.Find(context.TODO(), filter, &options.FindOptions{Limit: &limit, Sort: bson.M{"name": 1}})
+	if err != nil {
+		panic(err)
+	}
+
+	if err = cursor.All(context.TODO(), &results); err != nil {
+		panic(err)
+	}
+
+	return results
+}
+
+func FindStudio(filter bson.M) Studio {
+	var studio Studio
+	err := studioColl.FindOne(context.TODO(), filter).Decode(&studio)
+
+	if err == mongo.ErrNoDocuments {
+		fmt.Printf("No document was found\n")
+		return studio
+	}
+	if err != nil {
+		panic(err)
+	}
+
+	return studio
+}
+
+func AddStudio(studio Studio) (Studio, error) {
+	studio.Id = primitive.NewObjectID()
+	studio.Slug = UniqueSlug("studio", slugify(studio.Name), studio.Id)
+
+	_, err := studioColl.InsertOne(context.TODO(), studio)
+	// Another studio may have taken the slug since it was chosen
+	if mongo.IsDuplicateKeyError(err) {
+		studio.Slug = UniqueSlug("studio", slugify(studio.Name), studio.Id)
+		_, err = studioColl.InsertOne(context.TODO(), studio)
+	}
+	if err != nil {
+		return Studio{}, err
+	}
+
+	return studio, nil
+}
+
+func UpdateStudioById(idString string, data interface{}) int64 {
+	id, _ := primitive.ObjectIDFromHex(idString)
+
+	result, err := studioColl.UpdateOne(context.TODO(), bson.M{"_id": id}, bson.M{"$set": data})
+	if err != nil {
+		panic(err)
+	}
+
+	return result.ModifiedCount
+}
+
+func AddFilmsToStudio(idString string, films []string) (int64, error) {
+	id, err := primitive.ObjectIDFromHex(idString)
+	if err != nil {
+		return 0, err
+	}
+
+	result, err := studioColl.UpdateOne(context.TODO(), bson.M{"_id": id}, bson.M{"$addToSet": bson.M{"films": bson.M{"$each": films}}})
+	if err != nil {
+		return 0, err
+	}
+
+	return result.ModifiedCount, nil
+}
+
+func RemoveFilmsFromStudio(idString string, films []string) (int64, error) {
+	id, err := primitive.ObjectIDFromHex(idString)
+	if err != nil {
+		return 0, err
+	}
+
+	result, err := studioColl.UpdateOne(context.TODO(), bson.M{"_id": id}, bson.M{"$pull": bson.M{"films": bson.M{"$in": films}}})
+	if err != nil {
+		return 0, err
+	}
+
+	return result.ModifiedCount, nil
+}
+
+// FindStudioStats computes the statistics of the films the studio worked on
+func FindStudioStats(idString string) StudioStats {
+	var results []struct {
+		Roles     []RoleCount         `bson:"roles"`
+		Summary   []StudioStats       `bson:"summary"`
+		Directors []DirectorFilmCount `bson:"directors"`
+	}
+	aggregate(filmColl, []bson.M{
+		{"$match": bson.M{"studios.studio": idString}},
+		{"$facet": bson.M{
+			"roles": []bson.M{
+				{"$unwind": "$studios"},
+				{"$match": bson.M{"studios.studio": idString}},
+				{"$group": bson.M{"_id": "$studios.role", "count": bson.M{"$sum": 1}}},
+				{"$sort": bson.M{"_id": 1}},
+			},
+			"summary": []bson.M{
+				releaseYearStage,
+				{"$addFields": bson.M{"score": rtScoreExpression}},
+				{"$group": bson.M{
+					"_id":           nil,
+					"films":         bson.M{"$sum": 1},
+					"average_score": bson.M{"$avg": "$score"},
+					"first_year":    bson.M{"$min": "$year"},
+					"last_year":     bson.M{"$max": "$year"},
+				}},
+			},
+			"directors": append([]bson.M{
+				{"$unwind": "$directors"},
+				{"$group": bson.M{"_id": "$directors", "films": bson.M{"$sum": 1}}},
+				{"$sort": bson.D{{Key: "films", Value: -1}, {Key: "_id", Value: 1}}},
+				{"$limit": statsRankingSize},
+			}, lookupNameStages(directorColl.Name())...),
+		}},
+	}, &results)
+
+	stats := StudioStats{FilmsPerRole: []RoleCount{}, TopDirectors: []DirectorFilmCount{}}
+	if len(results) == 0 {
+		return stats
+	}
+	if len(results[0].Summary) > 0 {
+		stats = results[0].Summary[0]
+	}
+	stats.FilmsPerRole = results[0].Roles
+	stats.TopDirectors = results[0].Directors
+
+	return stats
+}
+
+func AreStudiosIdsValid(ids []string) (bool, gin.H) {
+	tempIds := make(map[string]struct{})
+	for i, id := range ids {
+		tempIds[id] = struct{}{}
+		if !primitive.IsValidObjectID(id) {
+			return false, gin.H{"message": fmt.Sprintf("Id of studio no. %v is invalid", i)}
+		}
+	}
+
+	studiosIds := make([]primitive.ObjectID, len(tempIds))
+	i := 0
+	for k := range tempIds {
+		studiosIds[i], _ = primitive.ObjectIDFromHex(k)
+		i++
+	}
+
+	result := FindStudios(bson.M{"_id": bson.M{"$in": studiosIds}}, len(studiosIds))
+
+	if len(result) != len(studiosIds) {
+		return false, gin.H{"message": "At least one studio id does not exists"}
+	}
+	return true, nil
+}
@@ -0,0 +1,164 @@
+package film_api
+
+import (
+	"golang.org/x/text/runes"
+	"golang.org/x/text/transform"
+	"golang.org/x/text/unicode/norm"
+	"strings"
+	"unicode"
+)
+
+// specialLettersReplacer replaces the latin letters which are not decomposed into a base letter and a diacritic
+var specialLettersReplacer = strings.NewReplacer(
+	"ß", "ss", "æ", "ae", "œ", "oe", "ø", "o", "đ", "d", "ð", "d", "ł", "l", "þ", "th",
+)
+
+// kanaRomaji maps the hiragana to their Hepburn romanization, katakana are converted to hiragana beforehand
+var kanaRomaji = map[string]string{
+	"あ": "a", "い": "i", "う": "u", "え": "e", "お": "o",
+	"か": "ka", "き": "ki", "く": "ku", "け": "ke", "こ": "ko",
+	"が": "ga", "ぎ": "gi", "ぐ": "gu", "げ": "ge", "ご": "go",
+	"さ": "sa", "し": "shi", "す": "su", "せ": "se", "そ": "so",
+	"ざ": "za", "じ": "ji", "ず": "zu", "ぜ": "ze", "ぞ": "zo",
+	"た": "ta", "ち": "chi", "つ": "tsu", "て": "te", "と": "to",
+	"だ": "da", "ぢ": "ji", "づ": "zu", "で": "de", "ど": "do",
+	"な": "na", "に": "ni", "ぬ": "nu", "ね": "ne", "の": "no",
+	"は": "ha", "ひ": "hi", "ふ": "fu", "へ": "he", "ほ": "ho",
+	"ば": "ba", "び": "bi", "ぶ": "bu", "べ": "be", "ぼ": "bo",
+	"ぱ": "pa", "ぴ": "pi", "ぷ": "pu", "ぺ": "pe", "ぽ": "po",
+	"ま": "ma", "み": "mi", "む": "mu", "め": "me", "も": "mo",
+	"や": "ya", "ゆ": "yu", "よ": "yo",
+	"ら": "ra", "り": "ri", "る": "ru", "れ": "re", "ろ": "ro",
+	"わ": "wa", "ゐ": "i", "ゑ": "e", "を": "o", "ん": "n", "ゔ": "vu",
+	"ぁ": "a", "ぃ": "i", "ぅ": "u", "ぇ": "e", "ぉ": "o", "ゎ": "wa",
+	"きゃ": "kya", "きゅ": "kyu", "きょ": "kyo", "ぎゃ": "gya", "ぎゅ": "gyu", "ぎょ": "gyo",
+	"しゃ": "sha", "しゅ": "shu", "しょ": "sho", "じゃ": "ja", "じゅ": "ju", "じょ": "jo",
+	"ちゃ": "cha", "ちゅ": "chu", "ちょ": "cho", "にゃ": "nya", "にゅ": "nyu", "にょ": "nyo",
+	"ひゃ": "hya", "ひゅ": "hyu", "ひょ": "hyo", "びゃ": "bya", "びゅ": "byu", "びょ": "byo",
+	"ぴゃ": "pya", "ぴゅ": "pyu", "ぴょ": "pyo", "みゃ": "mya", "みゅ": "myu", "みょ": "myo",
+	"りゃ": "rya", "りゅ": "ryu", "りょ": "ryo",
+	"ふぁ": "fa", "ふぃ": "fi", "ふぇ": "fe", "ふぉ": "fo", "てぃ": "ti", "でぃ": "di",
+	"うぃ": "wi", "うぇ": "we", "うぉ": "wo", "ゔぁ": "va", "ゔぃ": "vi", "ゔぇ": "ve", "ゔぉ": "vo",
+	"しぇ": "she", "じぇ": "je", "ちぇ": "che",
+}
+
+// cyrillicLatin maps the lowercase Cyrillic letters of the Slavic languages to their latin transliteration
+var cyrillicLatin = map[rune]string{
+	'а': "a", 'б': "b", 'в': "v", 'г': "g", 'д': "d", 'е': "e", 'ё': "yo", 'ж': "zh", 'з': "z", 'и': "i", 'й': "y",
+	'к': "k", 'л': "l", 'м': "m", 'н': "n", 'о': "o", 'п': "p", 'р': "r", 'с': "s", 'т': "t", 'у': "u", 'ф': "f",
+	'х': "kh", 'ц': "ts", 'ч': "ch", 'ш': "sh", 'щ': "shch", 'ъ': "", 'ы': "y", 'ь': "", 'э': "e", 'ю': "yu", 'я': "ya",
+	'є': "ye", 'і': "i", 'ї': "yi", 'ґ': "g", 'ў': "u",
+	'ђ': "dj", 'ј': "j", 'љ': "lj", 'њ': "nj", 'ћ': "c", 'џ': "dz", 'ѓ': "gj", 'ќ': "kj", 'ѕ': "dz",
+}
+
+// The Hangul syllables are made of an initial consonant, a vowel and an optional final consonant, romanized with the
+// Revised Romanization of Korean
+const (
+	hangulFirst = '가'
+	hangulLast  = '힣'
+)
+
+var (
+	hangulInitials = []string{"g", "kk", "n", "d", "tt", "r", "m", "b", "pp", "s", "ss", "", "j", "jj", "ch", "k", "t", "p", "h"}
+	hangulVowels   = []string{"a", "ae", "ya", "yae", "eo", "e", "yeo", "ye", "o", "wa", "wae", "oe", "yo", "u", "wo", "we", "wi", "yu", "eu", "ui", "i"}
+	hangulFinals   = []string{"", "k", "k", "k", "n", "n", "n", "t", "l", "k", "m", "l", "l", "l", "p", "l", "m", "p", "p", "t", "t", "ng", "t", "t", "k", "t", "p", "t"}
+)
+
+// foldText lowers the case of a text and removes its diacritics, so that "Émile" and "emile" are equal
+func foldText(text string) string {
+	// A transformer is not safe for concurrent use, so a new one is built for each call
+	removeDiacritics := transform.Chain(norm.NFD, runes.Remove(runes.In(unicode.Mn)), norm.NFC)
+	folded, _, err := transform.String(removeDiacritics, strings.ToLower(text))
+	if err != nil {
+		folded = strings.ToLower(text)
+	}
+
+	return specialLettersReplacer.Replace(folded)
+}
+
+// transliterate converts the Japanese kana, the Hangul and the Cyrillic letters of a text to latin letters, the other
+// characters, like the kanji, are kept as is
+func transliterate(text string) string {
+	chars := []rune(norm.NFC.String(text))
+	var builder strings.Builder
+
+	for i := 0; i < len(chars); i++ {
+		char := toHiragana(chars[i])
+
+		// The small tsu doubles the first consonant of the next kana
+		if char == 'っ' && i+1 < len(chars) {
+			next, _ := romanizeKana(chars, i+1)
+			if strings.HasPrefix(next, "ch") {
+				builder.WriteByte('t')
+			} else if len(next) > 0 && strings.IndexByte("aeiou", next[0]) < 0 {
+				builder.WriteByte(next[0])
+			}
+			continue
+		}
+
+		// The prolonged sound mark repeats the previous vowel
+		if char == 'ー' {
+			if written := builder.String(); len(written) > 0 {
+				builder.WriteByte(written[len(written)-1])
+			}
+			continue
+		}
+
+		if romaji, length := romanizeKana(chars, i); length > 0 {
+			builder.WriteString(romaji)
+			i += length - 1
+			continue
+		}
+
+		if latin, found := romanizeLetter(chars[i]); found {
+			builder.WriteString(latin)
+			continue
+		}
+
+		// Japanese punctuation is converted to spaces so that words stay separated
+		if char == '・' || char == '　' || char == '、' || char == '。' {
+			builder.WriteByte(' ')
+			continue
+		}
+		builder.WriteRune(chars[i])
+	}
+
+	return builder.String()
+}
+
+// romanizeKana returns the romanization of the kana at the given position, combined with the next small kana if any,
+// and the number of characters romanized, which is 0 if the character is not a kana
+func romanizeKana(chars []rune, i int) (string, int) {
+	char := toHiragana(chars[i])
+	if i+1 < len(chars) {
+		if romaji, found := kanaRomaji[string([]rune{char, toHiragana(chars[i+1])})]; found {
+			return romaji, 2
+		}
+	}
+	if romaji, found := kanaRomaji[string(char)]; found {
+		return romaji, 1
+	}
+
+	return "", 0
+}
+
+// romanizeLetter returns the romanization of a Hangul syllable, syllable by syllable without the sound changes between
+// them, or the transliteration of a Cyrillic letter
+func romanizeLetter(char rune) (string, bool) {
+	if char >= hangulFirst && char <= hangulLast {
+		index := int(char - hangulFirst)
+		return hangulInitials[index/588] + hangulVowels[index%588/28] + hangulFinals[index%28], true
+	}
+
+	latin, found := cyrillicLatin[unicode.ToLower(char)]
+	return latin, found
+}
+
+// toHiragana converts a katakana to the matching hiragana
+func toHiragana(char rune) rune {
+	if char >= 'ァ' && char <= 'ヴ' {
+		return char - 'ァ' + 'ぁ'
+	}
+
+	return char
+}
@@ -0,0 +1,27 @@
+package film_api
+
+import "testing"
+
+func TestTransliterate(t *testing.T) {
+	tests := []struct {
+		text string
+		want string
+	}{
+		{"となりのトトロ", "tonarinototoro"},
+		{"トトロ", "totoro"},
+		{"김기덕", "gimgideok"},
+		{"서울의 봄", "seoului bom"},
+		{"Андрей Тарковский", "andrey tarkovskiy"},
+		{"Їжак у тумані", "yizhak u tumani"},
+		{"Ёж", "yozh"},
+		// The kanji and the latin letters are kept as is
+		{"東京物語", "東京物語"},
+		{"Amélie", "Amélie"},
+	}
+
+	for _, test := range tests {
+		if got := transliterate(test.text); got != test.want {
+			t.Errorf("transliterate(%q) = %q, want %q", test.text, got, test.want)
+		}
+	}
+}
@@ -0,0 +1,427 @@
+package film_api
+
+import (
+	"filmflix/asset_storage"
+	"fmt"
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"net/http"
+	"os"
+	"sort"
+	"strconv"
+	"time"
+)
+
+// defaultTrashRetentionDays is the number of days the items stay in the trash before being purged, unless set with
+// the TRASH_RETENTION_DAYS environment variable
+const defaultTrashRetentionDays = 30
+
+// trashPurgeInterval is the interval between the purges of the trash
+const trashPurgeInterval = time.Hour
+
+// TrashedRole is a role removed from a film when its actor was put in the trash
+type TrashedRole struct {
+	FilmId string `json:"film" bson:"film"`
+	Role   Role   `json:"role" bson:"role"`
+}
+
+// TrashedCredit is a credit removed from a film when its person was put in the trash
+type TrashedCredit struct {
+	FilmId string `json:"film" bson:"film"`
+	Credit Credit `json:"credit" bson:"credit"`
+}
+
+// TrashedItem is a film or a person in the trash
+type TrashedItem struct {
+	Id        string    `json:"id"`
+	Kind      string    `json:"kind"` // Kind is film or person
+	Name      string    `json:"name"`
+	DeletedAt time.Time `json:"deleted_at"`
+	PurgeAt   time.Time `json:"purge_at"` // PurgeAt is when the item will be permanently deleted
+}
+
+func InitTrashApiRoutes(apiRoutes *gin.RouterGroup, client *mongo.Client) {
+	InitFilmCollection(client)
+	InitPersonCollection(client)
+	InitGenreCollection(client)
+	InitStudioCollection(client)
+	InitAwardCollection(client)
+	InitReviewCollection(client)
+	InitWatchlistCollections(client)
+	InitAuditCollection(client)
+	InitRevisionCollection(client)
+
+	trashRoutes := apiRoutes.Group("/admin/trash", RequireScope(adminScope))
+	trashRoutes.GET("/", GetTrash)
+	trashRoutes.POST("/films/:id/restore", Audit("film", filmColl), RestoreFilm)
+	trashRoutes.DELETE("/films/:id", Audit("film", filmColl), PurgeFilm)
+	trashRoutes.POST("/people/:id/restore", Audit("person", personColl), RestorePerson)
+	trashRoutes.DELETE("/people/:id", Audit("person", personColl), PurgePerson)
+}
+
+// StartTrashPurge permanently deletes the items which have been in the trash for longer than the retention period,
+// at once and then every hour
+func StartTrashPurge() {
+	go func() {
+		for {
+			runTrashPurge(time.Now().Add(-trashRetention()))
+			time.Sleep(trashPurgeInterval)
+		}
+	}()
+}
+
+// runTrashPurge purges the trash in the background, a failure being logged rather than crashing the server so that
+// the purge is tried again at the next interval
+func runTrashPurge(before time.Time) {
+	defer func() {
+		if err := recover(); err != nil {
+			fmt.Printf("Purge of the trash failed: %v\n", err)
+		}
+	}()
+
+	PurgeTrash(before)
+}
+
+// PurgeTrash permanently deletes the items put in the trash before the given time
+func PurgeTrash(before time.Time) {
+	filter := bson.M{"deleted_at": bson.M{"$lte": before}}
+
+	for _, film := range FindFilms(filter, 0) {
+		if err := purgeFilm(film.Id.Hex()); err != nil {
+			fmt.Printf("Purge of film %v failed: %v\n", film.Id.Hex(), err)
+		}
+	}
+	for _, person := range FindPeople(filter, 0) {
+		if err := purgePerson(person.Id.Hex()); err != nil {
+			fmt.Printf("Purge of person %v failed: %v\n", person.Id.Hex(), err)
+		}
+	}
+}
+
+// GetTrash returns the items in the trash, the most recently deleted first, ?kind only returning the films or the people
+func GetTrash(c *gin.Context) {
+	kind := c.Query("kind")
+	filter := bson.M{"deleted_at": bson.M{"$exists": true}}
+	retention := trashRetention()
+	items := []TrashedItem{}
+
+	if kind == "" || kind == "film" {
+		for _, film := range FindFilms(filter, 0) {
+			items = append(items, TrashedItem{Id: film.Id.Hex(), Kind: "film", Name: film.Title, DeletedAt: *film.DeletedAt, PurgeAt: film.DeletedAt.Add(retention)})
+		}
+	}
+	if kind == "" || kind == "person" {
+		for _, person := range FindPeople(filter, 0) {
+			items = append(items, TrashedItem{Id: person.Id.Hex(), Kind: "person", Name: person.Name, DeletedAt: *person.DeletedAt, PurgeAt: person.DeletedAt.Add(retention)})
+		}
+	}
+
+	sort.Slice(items, func(i, j int) bool {
+		return items[i].DeletedAt.After(items[j].DeletedAt)
+	})
+
+	c.IndentedJSON(http.StatusOK, items)
+}
+
+// RestoreFilm takes a film out of the trash and links it again to its people, genres and studios. The links to the
+// items deleted in the meantime are dropped.
+func RestoreFilm(c *gin.Context) {
+	film, ok := findTrashedFilm(c)
+	if !ok {
+		return
+	}
+	id := film.Id.Hex()
+
+	var people []string
+	for _, role := range film.Roles {
+		people = append(people, role.ActorId)
+	}
+	people = append(people, film.Directors...)
+	for _, credit := range film.Credits {
+		people = append(people, credit.PersonId)
+	}
+	livePeople := findLiveIds(personColl, people)
+	liveGenres := findLiveIds(genreColl, film.Genres)
+	liveStudios := findLiveIds(studioColl, creditedStudios(film.Studios))
+
+	roles := []Role{}
+	for _, role := range film.Roles {
+		if livePeople[role.ActorId] {
+			roles = append(roles, role)
+		}
+	}
+	credits := []Credit{}
+	var crew []string
+	for _, credit := range film.Credits {
+		if livePeople[credit.PersonId] {
+			credits = append(credits, credit)
+			if !isLegacyCredit(credit) && !containsString(crew, credit.PersonId) {
+				crew = append(crew, credit.PersonId)
+			}
+		}
+	}
+	studios := []StudioCredit{}
+	for _, studio := range film.Studios {
+		if liveStudios[studio.StudioId] {
+			studios = append(studios, studio)
+		}
+	}
+	directors := liveOnly(film.Directors, livePeople)
+	genres := liveOnly(film.Genres, liveGenres)
+
+	if UntrashItemById(filmColl, id) == 0 {
+		c.IndentedJSON(http.StatusNotModified, gin.H{"message": "No film with the specified id in the trash"})
+		return
+	}
+
+	if _, err := SetFilmCredits(id, credits, roles, directors); err != nil {
+		c.IndentedJSON(http.StatusInternalServerError, gin.H{"message": err.Error()})
+		return
+	}
+	if _, err := UpdateFilmById(id, bson.M{"genres": genres, "studios": studios}); err != nil {
+		c.IndentedJSON(http.StatusInternalServerError, gin.H{"message": err.Error()})
+		return
+	}
+
+	linkCreditedPeople(c, Film{Id: film.Id}, roles, directors, crew)
+	syncLinks(id, nil, genres, AddFilmsToGenre, RemoveFilmsFromGenre)
+	syncLinks(id, nil, creditedStudios(studios), AddFilmsToStudio, RemoveFilmsFromStudio)
+
+	c.IndentedJSON(http.StatusOK, FindFilm(bson.M{"_id": film.Id}))
+}
+
+// RestorePerson takes a person out of the trash and adds back the roles and the credits removed from the films. The
+// films deleted in the meantime are dropped.
+func RestorePerson(c *gin.Context) {
+	person, ok := findTrashedPerson(c)
+	if !ok {
+		return
+	}
+	id := person.Id.Hex()
+
+	films := append(append(append([]string{}, person.ActedFilms...), person.DirectedFilms...), person.Films...)
+	for _, role := range person.DeletedRoles {
+		films = append(films, role.FilmId)
+	}
+	for _, credit := range person.DeletedCredits {
+		films = append(films, credit.FilmId)
+	}
+	liveFilms := findLiveIds(filmColl, films)
+
+	if UntrashItemById(personColl, id) == 0 {
+		c.IndentedJSON(http.StatusNotModified, gin.H{"message": "No person with the specified id in the trash"})
+		return
+	}
+
+	UpdatePersonById(id, bson.M{
+		"acted_films":    liveOnly(person.ActedFilms, liveFilms),
+		"directed_films": liveOnly(person.DirectedFilms, liveFilms),
+		"films":          liveOnly(person.Films, liveFilms),
+	})
+
+	for _, role := range person.DeletedRoles {
+		if !liveFilms[role.FilmId] {
+			continue
+		}
+		if _, err := AddActorsToFilm(role.FilmId, []Role{role.Role}); err != nil {
+			c.IndentedJSON(http.StatusInternalServerError, gin.H{"message": err.Error()})
+			return
+		}
+	}
+	for _, film := range liveOnly(person.DirectedFilms, liveFilms) {
+		if _, err := AddDirectorsToFilm(film, []string{id}); err != nil {
+			c.IndentedJSON(http.StatusInternalServerError, gin.H{"message": err.Error()})
+			return
+		}
+	}
+	for _, credit := range person.DeletedCredits {
+		if !liveFilms[credit.FilmId] {
+			continue
+		}
+		if _, err := AddCreditsToFilm(credit.FilmId, []Credit{credit.Credit}); err != nil {
+			c.IndentedJSON(http.StatusInternalServerError, gin.H{"message": err.Error()})
+			return
+		}
+	}
+
+	c.IndentedJSON(http.StatusOK, FindPersonById(person.Id))
+}
+
+// PurgeFilm permanently deletes a film of the trash
+func PurgeFilm(c *gin.Context) {
+	film, ok := findTrashedFilm(c)
+	if !ok {
+		return
+	}
+
+	if err := purgeFilm(film.Id.Hex()); err != nil {
+		c.IndentedJSON(http.StatusInternalServerError, gin.H{"message": err.Error()})
+		return
+	}
+
+	c.IndentedJSON(http.StatusNoContent, gin.H{})
+}
+
+// PurgePerson permanently deletes a person of the trash
+func PurgePerson(c *gin.Context) {
+	person, ok := findTrashedPerson(c)
+	if !ok {
+		return
+	}
+
+	if err := purgePerson(person.Id.Hex()); err != nil {
+		c.IndentedJSON(http.StatusInternalServerError, gin.H{"message": err.Error()})
+		return
+	}
+
+	c.IndentedJSON(http.StatusNoContent, gin.H{})
+}
+
+// findActorRoles returns the roles of an actor on the films, which are only stored in the films
+func findActorRoles(id string) []TrashedRole {
+	roles := []TrashedRole{}
+	for _, film := range FindFilms(bson.M{"roles.actor": id}, 0) {
+		for _, role := range film.Roles {
+			if role.ActorId == id {
+				roles = append(roles, TrashedRole{FilmId: film.Id.Hex(), Role: role})
+			}
+		}
+	}
+	return roles
+}
+
+// trashPerson puts a person in the trash, and removes its roles and credits from the films after saving them in
+// the person so that they can be restored. Its awards are only unlinked when it is purged.
+func trashPerson(person Person) (int64, error) {
+	id := person.Id.Hex()
+
+	roles := findActorRoles(id)
+	credits := []TrashedCredit{}
+	for _, film := range FindFilms(bson.M{"credits.person": id}, 0) {
+		for _, credit := range film.Credits {
+			if credit.PersonId == id {
+				credits = append(credits, TrashedCredit{FilmId: film.Id.Hex(), Credit: credit})
+			}
+		}
+	}
+
+	result := TrashItemById(personColl, id, bson.M{"deleted_roles": roles, "deleted_credits": credits})
+	if result == 0 {
+		return 0, nil
+	}
+
+	for _, film := range person.ActedFilms {
+		if _, err := RemoveActorsFromFilm(film, []string{id}); err != nil {
+			return 0, err
+		}
+	}
+	for _, film := range person.DirectedFilms {
+		if _, err := RemoveDirectorsFromFilm(film, []string{id}); err != nil {
+			return 0, err
+		}
+	}
+	if _, err := RemovePersonFromCredits(id); err != nil {
+		return 0, err
+	}
+
+	return result, nil
+}
+
+// purgeFilm permanently deletes a film with its awards, reviews, entries of the user lists and poster
+func purgeFilm(id string) error {
+	if err := RemoveFilmFromLinkedItems(id); err != nil {
+		return err
+	}
+	if _, err := RemoveAwardsOfFilm(id); err != nil {
+		return err
+	}
+	if _, err := RemoveReviewsOfFilm(id); err != nil {
+		return err
+	}
+	if err := RemoveFilmFromUserLists(id); err != nil {
+		return err
+	}
+	if err := asset_storage.GetStorage().RemoveAll(posterDirectory(id)); err != nil {
+		return err
+	}
+
+	DeleteItemById(filmColl, id)
+	return nil
+}
+
+// purgePerson permanently deletes a person, whose awards are kept as awards of their films
+func purgePerson(id string) error {
+	if err := RemovePersonFromFilms(id); err != nil {
+		return err
+	}
+	for _, kind := range []string{"actor", "director"} {
+		if _, err := RemovePersonFromAwards(kind, id); err != nil {
+			return err
+		}
+	}
+
+	DeleteItemById(personColl, id)
+	return nil
+}
+
+// findTrashedFilm returns the film of the url if it is in the trash. If not, it answers with an error and returns false.
+func findTrashedFilm(c *gin.Context) (Film, bool) {
+	id, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		c.IndentedJSON(http.StatusBadRequest, gin.H{"message": "Id is invalid"})
+		return Film{}, false
+	}
+
+	films := FindFilms(bson.M{"_id": id, "deleted_at": bson.M{"$exists": true}}, 1)
+	if len(films) == 0 {
+		c.IndentedJSON(http.StatusNotFound, gin.H{"message": "No film with the specified id in the trash"})
+		return Film{}, false
+	}
+
+	return films[0], true
+}
+
+// findTrashedPerson returns the person of the url if it is in the trash. If not, it answers with an error and returns
+// false.
+func findTrashedPerson(c *gin.Context) (Person, bool) {
+	id, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		c.IndentedJSON(http.StatusBadRequest, gin.H{"message": "Id is invalid"})
+		return Person{}, false
+	}
+
+	people := FindPeople(bson.M{"_id": id, "deleted_at": bson.M{"$exists": true}}, 1)
+	if len(people) == 0 {
+		c.IndentedJSON(http.StatusNotFound, gin.H{"message": "No person with the specified id in the trash"})
+		return Person{}, false
+	}
+
+	return people[0], true
+}
+
+// isTrashed tells whether a raw document is in the trash
+func isTrashed(document bson.M) bool {
+	_, found := document["deleted_at"]
+	return found
+}
+
+// liveOnly returns the ids which are in the live set
+func liveOnly(ids []string, live map[string]bool) []string {
+	result := []string{}
+	for _, id := range ids {
+		if live[id] {
+			result = append(result, id)
+		}
+	}
+	return result
+}
+
+func trashRetention() time.Duration {
+	days := defaultTrashRetentionDays
+	if value, err := strconv.Atoi(os.Getenv("TRASH_RETENTION_DAYS")); err == nil && value >= 0 {
+		days = value
+	}
+	return time.Duration(days) * 24 * time.Hour
+}
@@ -0,0 +1,151 @@
+package film_api
+
+import (
+	"context"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"time"
+)
+
+// TrashItemById puts an item in the trash and returns the number of trashed items
+func TrashItemById(collection *mongo.Collection, idString string, data bson.M) int64 {
+	id, _ := primitive.ObjectIDFromHex(idString)
+
+	set := bson.M{"deleted_at": time.Now()}
+	for key, value := range data {
+		set[key] = value
+	}
+
+	result, err := collection.UpdateOne(context.TODO(), bson.M{"_id": id, "deleted_at": bson.M{"$exists": false}}, bson.M{"$set": set})
+	if err != nil {
+		panic(err)
+	}
+
+	catalogueChanged(catalogueItemType(collection), idString)
+
+	return result.ModifiedCount
+}
+
+// UntrashItemById takes an item out of the trash and returns the number of restored items
+func UntrashItemById(collection *mongo.Collection, idString string) int64 {
+	id, _ := primitive.ObjectIDFromHex(idString)
+
+	result, err := collection.UpdateOne(context.TODO(), bson.M{"_id": id, "deleted_at": bson.M{"$exists": true}},
+		bson.M{"$unset": bson.M{"deleted_at": "", "deleted_roles": "", "deleted_credits": ""}})
+	if err != nil {
+		panic(err)
+	}
+
+	catalogueChanged(catalogueItemType(collection), idString)
+
+	return result.ModifiedCount
+}
+
+// withoutTrashedFilms returns a copy of the filter of items linked to a film, like the reviews, leaving out the items
+// of the films in the trash
+func withoutTrashedFilms(filter bson.M) bson.M {
+	var results []struct {
+		Id primitive.ObjectID `bson:"_id"`
+	}
+	cursor, err := filmColl.Find(context.TODO(), bson.M{"deleted_at": bson.M{"$exists": true}}, &options.FindOptions{Projection: bson.M{"_id": 1}})
+	if err != nil {
+		panic(err)
+	}
+
+	if err = cursor.All(context.TODO(), &results); err != nil {
+		panic(err)
+	}
+
+	if len(results) == 0 {
+		return filter
+	}
+	trashed := make([]string, len(results))
+	for i, result := range results {
+		trashed[i] = result.Id.Hex()
+	}
+	return bson.M{"$and": []bson.M{filter, {"film": bson.M{"$nin": trashed}}}}
+}
+
+// findLiveIds returns the ids of the items of the collection which exist and are not in the trash
+func findLiveIds(collection *mongo.Collection, ids []string) map[string]bool {
+	objectIds := []primitive.ObjectID{}
+	for _, idString := range ids {
+		if id, err := primitive.ObjectIDFromHex(idString); err == nil {
+			objectIds = append(objectIds, id)
+		}
+	}
+
+	var results []struct {
+		Id primitive.ObjectID `bson:"_id"`
+	}
+	cursor, err := collection.Find(context.TODO(), notDeleted(bson.M{"_id": bson.M{"$in": objectIds}}), &options.FindOptions{Projection: bson.M{"_id": 1}})
+	if err != nil {
+		panic(err)
+	}
+
+	if err = cursor.All(context.TODO(), &results); err != nil {
+		panic(err)
+	}
+
+	live := make(map[string]bool, len(results))
+	for _, result := range results {
+		live[result.Id.Hex()] = true
+	}
+	return live
+}
+
+// AddCreditsToFilm adds credits back to a film
+func AddCreditsToFilm(idString string, credits []Credit) (int64, error) {
+	id, err := primitive.ObjectIDFromHex(idString)
+	if err != nil {
+		return 0, err
+	}
+
+	result, err := filmColl.UpdateOne(context.TODO(), bson.M{"_id": id}, bson.M{"$push": bson.M{"credits": bson.M{"$each": credits}}})
+	if err != nil {
+		return 0, err
+	}
+
+	catalogueChanged("film", idString)
+
+	return result.ModifiedCount, nil
+}
+
+// RemoveFilmFromLinkedItems removes a film from the people, the genres and the studios, including the ones in the trash
+func RemoveFilmFromLinkedItems(filmId string) error {
+	_, err := personColl.UpdateMany(context.TODO(),
+		bson.M{"$or": []bson.M{{"acted_films": filmId}, {"directed_films": filmId}, {"films": filmId}}},
+		bson.M{"$pull": bson.M{"acted_films": filmId, "directed_films": filmId, "films": filmId}},
+	)
+	if err != nil {
+		return err
+	}
+
+	for _, collection := range []*mongo.Collection{genreColl, studioColl} {
+		if _, err = collection.UpdateMany(context.TODO(), bson.M{"films": filmId}, bson.M{"$pull": bson.M{"films": filmId}}); err != nil {
+			return err
+		}
+	}
+
+	catalogueChanged("film", filmId)
+
+	return nil
+}
+
+// RemovePersonFromFilms removes the roles, the directions and the credits of a person from all the films, including
+// the ones in the trash
+func RemovePersonFromFilms(personId string) error {
+	_, err := filmColl.UpdateMany(context.TODO(),
+		bson.M{"$or": []bson.M{{"roles.actor": personId}, {"directors": personId}, {"credits.person": personId}}},
+		bson.M{"$pull": bson.M{"roles": bson.M{"actor": personId}, "directors": personId, "credits": bson.M{"person": personId}}},
+	)
+	if err != nil {
+		return err
+	}
+
+	catalogueChanged("person", personId)
+
+	return nil
+}
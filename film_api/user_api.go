@@ -0,0 +1,129 @@
+package film_api
+
+import (
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"golang.org/x/crypto/bcrypt"
+	"net/http"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+const minPasswordLength = 8
+
+type User struct {
+	Id           primitive.ObjectID `json:"id" bson:"_id,omitempty"`
+	Name         string             `json:"name" bson:"name"` // Name is the unique name the user logs in with
+	PasswordHash string             `json:"-" bson:"password_hash"`
+	Role         string             `json:"role" bson:"role"` // Role is viewer, editor or admin
+	CreatedAt    time.Time          `json:"created_at" bson:"created_at"`
+
+	WatchlistPublic bool `json:"watchlist_public" bson:"watchlist_public"`
+	WatchedPublic   bool `json:"watched_public" bson:"watched_public"`
+}
+
+type SignUpReq struct {
+	Name     string `json:"name"`
+	Password string `json:"password"`
+}
+
+func InitUserApiRoutes(apiRoutes *gin.RouterGroup, client *mongo.Client) {
+	InitUserCollection(client)
+	InitReviewCollection(client)
+	InitWatchlistCollections(client)
+
+	userRoutes := apiRoutes.Group("/users")
+	userRoutes.POST("/", PostUser)
+	userRoutes.GET("/:id", GetUserById)
+	userRoutes.GET("/:id/reviews", GetUserReviews)
+	initWatchlistApiRoutes(userRoutes)
+}
+
+// PostUser registers a new viewer, who can then log in with his name and password
+func PostUser(c *gin.Context) {
+	var req SignUpReq
+	if err := c.BindJSON(&req); err != nil {
+		c.IndentedJSON(http.StatusBadRequest, gin.H{"message": "JSON is invalid"})
+		return
+	}
+
+	req.Name = strings.TrimSpace(req.Name)
+	if len(req.Name) == 0 || utf8.RuneCountInString(req.Password) < minPasswordLength {
+		c.IndentedJSON(http.StatusBadRequest, gin.H{"message": "The name is required and the password must be at least 8 characters long"})
+		return
+	}
+
+	hash, err := bcrypt.GenerateFromPassword([]byte(req.Password), bcrypt.DefaultCost)
+	if err != nil {
+		c.IndentedJSON(http.StatusBadRequest, gin.H{"message": err.Error()})
+		return
+	}
+
+	newUser, err := AddUser(User{Name: req.Name, PasswordHash: string(hash), Role: viewerRole})
+	if mongo.IsDuplicateKeyError(err) {
+		c.IndentedJSON(http.StatusConflict, gin.H{"message": "This name is already used"})
+		return
+	}
+	if err != nil {
+		c.IndentedJSON(http.StatusInternalServerError, gin.H{"message": err.Error()})
+		return
+	}
+
+	c.IndentedJSON(http.StatusCreated, newUser)
+}
+
+func GetUserById(c *gin.Context) {
+	id, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		c.IndentedJSON(http.StatusBadRequest, gin.H{"message": "Id is invalid"})
+		return
+	}
+
+	user := FindUser(bson.M{"_id": id})
+	if user.Id.IsZero() {
+		c.IndentedJSON(http.StatusNotFound, gin.H{"message": "User not found"})
+		return
+	}
+
+	c.IndentedJSON(http.StatusOK, user)
+}
+
+// authenticateUser returns the user whose access token is given with "Authorization: Bearer <token>".
+// If it is missing or invalid, it answers with an error and returns false.
+func authenticateUser(c *gin.Context) (User, bool) {
+	if user, _, ok := tokenUser(bearerToken(c)); ok {
+		c.Set("user", user)
+		return user, true
+	}
+
+	c.Header("WWW-Authenticate", `Bearer realm="filmflix"`)
+	c.IndentedJSON(http.StatusUnauthorized, gin.H{"message": "Authentication failed"})
+	return User{}, false
+}
+
+// tokenUser returns the user of a valid access token along with its claims, unless the user was deleted since the
+// token was issued
+func tokenUser(token string) (User, TokenClaims, bool) {
+	claims, err := parseAccessToken(token)
+	if err != nil {
+		return User{}, TokenClaims{}, false
+	}
+	id, err := primitive.ObjectIDFromHex(claims.Subject)
+	if err != nil {
+		return User{}, TokenClaims{}, false
+	}
+
+	user := FindUser(bson.M{"_id": id})
+	return user, claims, !user.Id.IsZero()
+}
+
+// role returns the role of the user, the users registered before the roles being viewers
+func (user User) role() string {
+	if len(user.Role) == 0 {
+		return viewerRole
+	}
+	return user.Role
+}
@@ -0,0 +1,68 @@
+package film_api
+
+import (
+	"context"
+	"filmflix/db_connection"
+	"fmt"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"time"
+)
+
+var userColl *mongo.Collection
+
+func InitUserCollection(client *mongo.Client) {
+	userColl = db_connection.GetCollection(client, "films", "users")
+
+	// The users log in with their name
+	_, err := userColl.Indexes().CreateOne(context.TODO(), mongo.IndexModel{
+		Keys:    bson.D{{Key: "name", Value: 1}},
+		Options: options.Index().SetUnique(true),
+	})
+	if err != nil {
+		panic(err)
+	}
+}
+
+func FindUser(filter bson.M) User {
+	var user User
+	err := userColl.FindOne(context.TODO(), filter).Decode(&user)
+
+	if err == mongo.ErrNoDocuments {
+		fmt.Printf("No document was found\n")
+		return user
+	}
+	if err != nil {
+		panic(err)
+	}
+
+	return user
+}
+
+func UpdateUserById(idString string, data interface{}) (int64, error) {
+	id, err := primitive.ObjectIDFromHex(idString)
+	if err != nil {
+		return 0, err
+	}
+
+	result, err := userColl.UpdateOne(context.TODO(), bson.M{"_id": id}, bson.M{"$set": data})
+	if err != nil {
+		return 0, err
+	}
+
+	return result.ModifiedCount, nil
+}
+
+func AddUser(user User) (User, error) {
+	user.Id = primitive.NewObjectID()
+	user.CreatedAt = time.Now()
+
+	_, err := userColl.InsertOne(context.TODO(), user)
+	if err != nil {
+		return User{}, err
+	}
+
+	return user, nil
+}
@@ -0,0 +1,524 @@
+package film_api
+
+import (
+	"fmt"
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// WatchlistEntry is a film a user wants to watch
+type WatchlistEntry struct {
+	Id       primitive.ObjectID `json:"id" bson:"_id,omitempty"`
+	UserId   string             `json:"user" bson:"user"`
+	FilmId   string             `json:"film" bson:"film"`
+	Position int                `json:"position" bson:"position"` // Position is the rank of the film in the watchlist, starting at 0
+	Note     string             `json:"note" bson:"note,omitempty"`
+	AddedAt  time.Time          `json:"added_at" bson:"added_at"`
+}
+
+// WatchedEntry is a film a user watched
+type WatchedEntry struct {
+	Id        primitive.ObjectID `json:"id" bson:"_id,omitempty"`
+	UserId    string             `json:"user" bson:"user"`
+	FilmId    string             `json:"film" bson:"film"`
+	WatchedAt time.Time          `json:"watched_at" bson:"watched_at"`
+	Rating    int                `json:"rating,omitempty" bson:"rating,omitempty"` // Rating is between 1 and 10, or 0 if the user did not rate the film
+	Rewatch   bool               `json:"rewatch" bson:"rewatch"`
+	CreatedAt time.Time          `json:"created_at" bson:"created_at"`
+}
+
+type WatchlistEntryReq struct {
+	FilmId   string `json:"film"`
+	Position *int   `json:"position"` // Position is optional, the film is added at the end of the watchlist by default
+	Note     string `json:"note"`
+}
+
+type WatchedEntryReq struct {
+	FilmId    string     `json:"film"`
+	WatchedAt *time.Time `json:"watched_at"` // WatchedAt is optional, it is the current time by default
+	Rating    int        `json:"rating"`
+	Rewatch   bool       `json:"rewatch"`
+}
+
+type PrivacyReq struct {
+	WatchlistPublic *bool `json:"watchlist_public"`
+	WatchedPublic   *bool `json:"watched_public"`
+}
+
+func initWatchlistApiRoutes(userRoutes *gin.RouterGroup) {
+	userRoutes.PATCH("/:id/privacy", UpdatePrivacy)
+
+	userRoutes.GET("/:id/watchlist", GetWatchlist)
+	userRoutes.POST("/:id/watchlist", PostWatchlistEntry)
+	userRoutes.GET("/:id/watchlist/export", ExportWatchlist)
+	userRoutes.POST("/:id/watchlist/import", ImportWatchlist)
+	userRoutes.PATCH("/:id/watchlist/:film", UpdateWatchlistEntry)
+	userRoutes.DELETE("/:id/watchlist/:film", DeleteWatchlistEntry)
+
+	userRoutes.GET("/:id/watched", GetWatched)
+	userRoutes.POST("/:id/watched", PostWatchedEntry)
+	userRoutes.GET("/:id/watched/export", ExportWatched)
+	userRoutes.POST("/:id/watched/import", ImportWatched)
+	userRoutes.PATCH("/:id/watched/:entry", UpdateWatchedEntry)
+	userRoutes.DELETE("/:id/watched/:entry", DeleteWatchedEntry)
+}
+
+// authenticateOwner returns the authenticated user if he is the user of the url.
+// Otherwise, it answers with an error and returns false.
+func authenticateOwner(c *gin.Context) (User, bool) {
+	user, ok := authenticateUser(c)
+	if !ok {
+		return User{}, false
+	}
+
+	if user.Id.Hex() != c.Param("id") {
+		c.IndentedJSON(http.StatusForbidden, gin.H{"message": "Only the owner can edit his lists"})
+		return User{}, false
+	}
+
+	return user, true
+}
+
+// canViewList tells whether the list of the user of the url can be read by the client: public lists can be read
+// by everyone, private ones only by their owner. If it can't, it answers with an error.
+func canViewList(c *gin.Context, public func(User) bool) bool {
+	id, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		c.IndentedJSON(http.StatusBadRequest, gin.H{"message": "Id is invalid"})
+		return false
+	}
+
+	user := FindUser(bson.M{"_id": id})
+	if user.Id.IsZero() {
+		c.IndentedJSON(http.StatusNotFound, gin.H{"message": "User not found"})
+		return false
+	}
+	if public(user) {
+		return true
+	}
+
+	_, ok := authenticateOwner(c)
+	return ok
+}
+
+func isWatchlistPublic(user User) bool {
+	return user.WatchlistPublic
+}
+
+func isWatchedPublic(user User) bool {
+	return user.WatchedPublic
+}
+
+func UpdatePrivacy(c *gin.Context) {
+	user, ok := authenticateOwner(c)
+	if !ok {
+		return
+	}
+
+	var req PrivacyReq
+	if err := c.BindJSON(&req); err != nil {
+		c.IndentedJSON(http.StatusBadRequest, gin.H{"message": "JSON is invalid"})
+		return
+	}
+
+	if req.WatchlistPublic != nil {
+		user.WatchlistPublic = *req.WatchlistPublic
+	}
+	if req.WatchedPublic != nil {
+		user.WatchedPublic = *req.WatchedPublic
+	}
+
+	if _, err := UpdateUserById(user.Id.Hex(), bson.M{"watchlist_public": user.WatchlistPublic, "watched_public": user.WatchedPublic}); err != nil {
+		c.IndentedJSON(http.StatusInternalServerError, gin.H{"message": err.Error()})
+		return
+	}
+
+	c.IndentedJSON(http.StatusOK, user)
+}
+
+func GetWatchlist(c *gin.Context) {
+	if !canViewList(c, isWatchlistPublic) {
+		return
+	}
+
+	c.IndentedJSON(http.StatusOK, FindWatchlist(c.Param("id")))
+}
+
+func PostWatchlistEntry(c *gin.Context) {
+	user, ok := authenticateOwner(c)
+	if !ok {
+		return
+	}
+
+	var req WatchlistEntryReq
+	if err := c.BindJSON(&req); err != nil {
+		c.IndentedJSON(http.StatusBadRequest, gin.H{"message": "JSON is invalid"})
+		return
+	}
+
+	if valid, msg := AreFilmsIdsValid([]string{req.FilmId}); !valid {
+		c.IndentedJSON(http.StatusBadRequest, msg)
+		return
+	}
+	if existing := FindWatchlistEntry(user.Id.Hex(), req.FilmId); !existing.Id.IsZero() {
+		c.IndentedJSON(http.StatusConflict, gin.H{"message": "The film is already in the watchlist"})
+		return
+	}
+
+	position := -1
+	if req.Position != nil {
+		position = *req.Position
+	}
+
+	entry, err := AddToWatchlist(WatchlistEntry{UserId: user.Id.Hex(), FilmId: req.FilmId, Position: position, Note: req.Note, AddedAt: time.Now()})
+	if err != nil {
+		c.IndentedJSON(http.StatusInternalServerError, gin.H{"message": err.Error()})
+		return
+	}
+
+	c.IndentedJSON(http.StatusCreated, entry)
+}
+
+// UpdateWatchlistEntry moves a film of the watchlist and updates its note
+func UpdateWatchlistEntry(c *gin.Context) {
+	user, ok := authenticateOwner(c)
+	if !ok {
+		return
+	}
+
+	var req WatchlistEntryReq
+	if err := c.BindJSON(&req); err != nil {
+		c.IndentedJSON(http.StatusBadRequest, gin.H{"message": "JSON is invalid"})
+		return
+	}
+
+	entry := FindWatchlistEntry(user.Id.Hex(), c.Param("film"))
+	if entry.Id.IsZero() {
+		c.IndentedJSON(http.StatusNotFound, gin.H{"message": "The film is not in the watchlist"})
+		return
+	}
+
+	position := entry.Position
+	if req.Position != nil {
+		position = *req.Position
+	}
+	entry.Note = req.Note
+
+	entry, err := MoveWatchlistEntry(entry, position)
+	if err != nil {
+		c.IndentedJSON(http.StatusInternalServerError, gin.H{"message": err.Error()})
+		return
+	}
+
+	c.IndentedJSON(http.StatusOK, entry)
+}
+
+func DeleteWatchlistEntry(c *gin.Context) {
+	user, ok := authenticateOwner(c)
+	if !ok {
+		return
+	}
+
+	entry := FindWatchlistEntry(user.Id.Hex(), c.Param("film"))
+	if entry.Id.IsZero() {
+		c.IndentedJSON(http.StatusNotModified, gin.H{"message": "The film is not in the watchlist"})
+		return
+	}
+
+	result, err := RemoveFromWatchlist(entry)
+	if err != nil {
+		c.IndentedJSON(http.StatusInternalServerError, gin.H{"message": err.Error()})
+		return
+	}
+
+	c.IndentedJSON(http.StatusNoContent, result)
+}
+
+// ExportWatchlist sends the watchlist as a Letterboxd compatible CSV file
+func ExportWatchlist(c *gin.Context) {
+	if !canViewList(c, isWatchlistPublic) {
+		return
+	}
+
+	entries := FindWatchlist(c.Param("id"))
+	filmsIds := make([]string, len(entries))
+	for i, entry := range entries {
+		filmsIds[i] = entry.FilmId
+	}
+	films := filmsByIds(filmsIds)
+
+	records := make([][]string, 0, len(entries))
+	for _, entry := range entries {
+		film := films[entry.FilmId]
+		records = append(records, []string{entry.AddedAt.Format(letterboxdDateLayout), film.Title, formatYear(film), ""})
+	}
+
+	writeLetterboxdCSV(c, "watchlist.csv", letterboxdWatchlistHeader, records)
+}
+
+// ImportWatchlist adds the films of a Letterboxd watchlist CSV file at the end of the watchlist
+func ImportWatchlist(c *gin.Context) {
+	user, ok := authenticateOwner(c)
+	if !ok {
+		return
+	}
+
+	rows, err := readLetterboxdCSV(c)
+	if err != nil {
+		c.IndentedJSON(http.StatusBadRequest, gin.H{"message": err.Error()})
+		return
+	}
+
+	report := ImportReport{Skipped: []string{}}
+	for _, row := range rows {
+		film, found := matchImportedFilm(row)
+		if !found || !FindWatchlistEntry(user.Id.Hex(), film.Id.Hex()).Id.IsZero() {
+			report.Skipped = append(report.Skipped, row["Name"])
+			continue
+		}
+
+		addedAt, valid := parseLetterboxdDate(row["Date"])
+		if !valid {
+			addedAt = time.Now()
+		}
+
+		if _, err := AddToWatchlist(WatchlistEntry{UserId: user.Id.Hex(), FilmId: film.Id.Hex(), Position: -1, AddedAt: addedAt}); err != nil {
+			c.IndentedJSON(http.StatusInternalServerError, gin.H{"message": err.Error()})
+			return
+		}
+		report.Imported++
+	}
+
+	c.IndentedJSON(http.StatusOK, report)
+}
+
+func GetWatched(c *gin.Context) {
+	if !canViewList(c, isWatchedPublic) {
+		return
+	}
+
+	limit := 0
+	if l := c.Query("limit"); len(l) > 0 {
+		limit, _ = strconv.Atoi(l)
+	}
+
+	c.IndentedJSON(http.StatusOK, FindWatched(c.Param("id"), limit))
+}
+
+func PostWatchedEntry(c *gin.Context) {
+	user, ok := authenticateOwner(c)
+	if !ok {
+		return
+	}
+
+	var req WatchedEntryReq
+	if err := c.BindJSON(&req); err != nil {
+		c.IndentedJSON(http.StatusBadRequest, gin.H{"message": "JSON is invalid"})
+		return
+	}
+
+	entry, msg := watchedEntryFromReq(req)
+	if msg != nil {
+		c.IndentedJSON(http.StatusBadRequest, msg)
+		return
+	}
+	entry.UserId = user.Id.Hex()
+	entry.CreatedAt = time.Now()
+
+	entry, err := AddWatchedEntry(entry)
+	if err != nil {
+		c.IndentedJSON(http.StatusInternalServerError, gin.H{"message": err.Error()})
+		return
+	}
+
+	c.IndentedJSON(http.StatusCreated, entry)
+}
+
+func UpdateWatchedEntry(c *gin.Context) {
+	user, ok := authenticateOwner(c)
+	if !ok {
+		return
+	}
+
+	entryId, err := primitive.ObjectIDFromHex(c.Param("entry"))
+	if err != nil {
+		c.IndentedJSON(http.StatusBadRequest, gin.H{"message": "Id is invalid"})
+		return
+	}
+
+	oldEntry := FindWatchedEntry(bson.M{"_id": entryId, "user": user.Id.Hex()})
+	if oldEntry.Id.IsZero() {
+		c.IndentedJSON(http.StatusNotFound, gin.H{"message": "Entry not found"})
+		return
+	}
+
+	var req WatchedEntryReq
+	if err := c.BindJSON(&req); err != nil {
+		c.IndentedJSON(http.StatusBadRequest, gin.H{"message": "JSON is invalid"})
+		return
+	}
+	if len(req.FilmId) == 0 {
+		req.FilmId = oldEntry.FilmId
+	}
+	if req.WatchedAt == nil {
+		req.WatchedAt = &oldEntry.WatchedAt
+	}
+
+	entry, msg := watchedEntryFromReq(req)
+	if msg != nil {
+		c.IndentedJSON(http.StatusBadRequest, msg)
+		return
+	}
+	entry.Id, entry.UserId, entry.CreatedAt = oldEntry.Id, oldEntry.UserId, oldEntry.CreatedAt
+
+	if _, err := ReplaceWatchedEntry(entry); err != nil {
+		c.IndentedJSON(http.StatusInternalServerError, gin.H{"message": err.Error()})
+		return
+	}
+
+	c.IndentedJSON(http.StatusOK, entry)
+}
+
+func DeleteWatchedEntry(c *gin.Context) {
+	user, ok := authenticateOwner(c)
+	if !ok {
+		return
+	}
+
+	entryId, err := primitive.ObjectIDFromHex(c.Param("entry"))
+	if err != nil {
+		c.IndentedJSON(http.StatusBadRequest, gin.H{"message": "Id is invalid"})
+		return
+	}
+
+	entry := FindWatchedEntry(bson.M{"_id": entryId, "user": user.Id.Hex()})
+	if entry.Id.IsZero() {
+		c.IndentedJSON(http.StatusNotModified, gin.H{"message": "No entry with the specified id"})
+		return
+	}
+
+	result := DeleteUserItemById(watchedColl, entryId.Hex())
+
+	c.IndentedJSON(http.StatusNoContent, result)
+}
+
+// ExportWatched sends the watched log as a Letterboxd compatible diary CSV file
+func ExportWatched(c *gin.Context) {
+	if !canViewList(c, isWatchedPublic) {
+		return
+	}
+
+	entries := FindWatched(c.Param("id"), 0)
+	filmsIds := make([]string, len(entries))
+	for i, entry := range entries {
+		filmsIds[i] = entry.FilmId
+	}
+	films := filmsByIds(filmsIds)
+
+	records := make([][]string, 0, len(entries))
+	for _, entry := range entries {
+		film := films[entry.FilmId]
+		rewatch := ""
+		if entry.Rewatch {
+			rewatch = "Yes"
+		}
+		records = append(records, []string{
+			entry.CreatedAt.Format(letterboxdDateLayout),
+			film.Title,
+			formatYear(film),
+			"",
+			formatLetterboxdRating(entry.Rating),
+			rewatch,
+			"",
+			entry.WatchedAt.Format(letterboxdDateLayout),
+		})
+	}
+
+	writeLetterboxdCSV(c, "diary.csv", letterboxdDiaryHeader, records)
+}
+
+// ImportWatched adds the films of a Letterboxd diary (or watched) CSV file to the watched log, skipping the ones
+// already logged on the same day
+func ImportWatched(c *gin.Context) {
+	user, ok := authenticateOwner(c)
+	if !ok {
+		return
+	}
+
+	rows, err := readLetterboxdCSV(c)
+	if err != nil {
+		c.IndentedJSON(http.StatusBadRequest, gin.H{"message": err.Error()})
+		return
+	}
+
+	report := ImportReport{Skipped: []string{}}
+	for _, row := range rows {
+		film, found := matchImportedFilm(row)
+		if !found {
+			report.Skipped = append(report.Skipped, row["Name"])
+			continue
+		}
+
+		// The watched log of Letterboxd only has the logging date, the diary has the watching date too
+		loggedAt, valid := parseLetterboxdDate(row["Date"])
+		if !valid {
+			loggedAt = time.Now()
+		}
+		watchedAt, valid := parseLetterboxdDate(row["Watched Date"])
+		if !valid {
+			watchedAt = loggedAt
+		}
+
+		// A film watched on a day already in the watched log was imported before
+		day := time.Date(watchedAt.Year(), watchedAt.Month(), watchedAt.Day(), 0, 0, 0, 0, watchedAt.Location())
+		if !FindWatchedEntry(bson.M{"user": user.Id.Hex(), "film": film.Id.Hex(), "watched_at": bson.M{"$gte": day, "$lt": day.AddDate(0, 0, 1)}}).Id.IsZero() {
+			report.Skipped = append(report.Skipped, row["Name"])
+			continue
+		}
+
+		entry := WatchedEntry{
+			UserId:    user.Id.Hex(),
+			FilmId:    film.Id.Hex(),
+			WatchedAt: watchedAt,
+			Rating:    parseLetterboxdRating(row["Rating"]),
+			Rewatch:   row["Rewatch"] == "Yes",
+			CreatedAt: loggedAt,
+		}
+		if _, err := AddWatchedEntry(entry); err != nil {
+			c.IndentedJSON(http.StatusInternalServerError, gin.H{"message": err.Error()})
+			return
+		}
+		report.Imported++
+	}
+
+	c.IndentedJSON(http.StatusOK, report)
+}
+
+// watchedEntryFromReq checks the request and returns the entry it describes, or the error message if it is invalid
+func watchedEntryFromReq(req WatchedEntryReq) (WatchedEntry, gin.H) {
+	if valid, msg := AreFilmsIdsValid([]string{req.FilmId}); !valid {
+		return WatchedEntry{}, msg
+	}
+	if req.Rating != 0 && (req.Rating < minRating || req.Rating > maxRating) {
+		return WatchedEntry{}, gin.H{"message": "The rating must be between 1 and 10"}
+	}
+
+	watchedAt := time.Now()
+	if req.WatchedAt != nil {
+		watchedAt = *req.WatchedAt
+	}
+
+	return WatchedEntry{FilmId: req.FilmId, WatchedAt: watchedAt, Rating: req.Rating, Rewatch: req.Rewatch}, nil
+}
+
+// formatYear returns the release year of a film, or an empty string if it is unknown
+func formatYear(film Film) string {
+	if year := film.Release.Year(); year != 0 {
+		return fmt.Sprint(year)
+	}
+	return ""
+}
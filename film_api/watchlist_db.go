@@ -0,0 +1,200 @@
+package film_api
+
+import (
+	"context"
+	"filmflix/db_connection"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+var watchlistColl *mongo.Collection
+var watchedColl *mongo.Collection
+
+func InitWatchlistCollections(client *mongo.Client) {
+	watchlistColl = db_connection.GetCollection(client, "films", "watchlists")
+	watchedColl = db_connection.GetCollection(client, "films", "watched")
+}
+
+// FindWatchlist returns the watchlist of a user without the films in the trash, in the order chosen by the user
+func FindWatchlist(userId string) []WatchlistEntry {
+	results := []WatchlistEntry{}
+	cursor, err := watchlistColl.Find(context.TODO(), withoutTrashedFilms(bson.M{"user": userId}), options.Find().SetSort(bson.M{"position": 1}))
+	if err != nil {
+		panic(err)
+	}
+
+	if err = cursor.All(context.TODO(), &results); err != nil {
+		panic(err)
+	}
+
+	return results
+}
+
+func FindWatchlistEntry(userId string, filmId string) WatchlistEntry {
+	var entry WatchlistEntry
+	err := watchlistColl.FindOne(context.TODO(), bson.M{"user": userId, "film": filmId}).Decode(&entry)
+
+	if err == mongo.ErrNoDocuments {
+		return entry
+	}
+	if err != nil {
+		panic(err)
+	}
+
+	return entry
+}
+
+func countWatchlist(userId string) int {
+	count, err := watchlistColl.CountDocuments(context.TODO(), bson.M{"user": userId})
+	if err != nil {
+		panic(err)
+	}
+	return int(count)
+}
+
+// AddToWatchlist inserts the entry at its position in the watchlist, or at its end if the position is out of the list
+func AddToWatchlist(entry WatchlistEntry) (WatchlistEntry, error) {
+	count := countWatchlist(entry.UserId)
+	if entry.Position < 0 || entry.Position > count {
+		entry.Position = count
+	}
+
+	// The next entries are shifted to make room for the new one
+	_, err := watchlistColl.UpdateMany(context.TODO(),
+		bson.M{"user": entry.UserId, "position": bson.M{"$gte": entry.Position}},
+		bson.M{"$inc": bson.M{"position": 1}},
+	)
+	if err != nil {
+		return WatchlistEntry{}, err
+	}
+
+	entry.Id = primitive.NewObjectID()
+	if _, err := watchlistColl.InsertOne(context.TODO(), entry); err != nil {
+		return WatchlistEntry{}, err
+	}
+
+	return entry, nil
+}
+
+// MoveWatchlistEntry moves the entry to the given position, shifting the entries between its old and new positions
+func MoveWatchlistEntry(entry WatchlistEntry, position int) (WatchlistEntry, error) {
+	count := countWatchlist(entry.UserId)
+	if position < 0 || position >= count {
+		position = count - 1
+	}
+
+	var shifted bson.M
+	var shift int
+	if position < entry.Position {
+		shifted, shift = bson.M{"$gte": position, "$lt": entry.Position}, 1
+	} else {
+		shifted, shift = bson.M{"$gt": entry.Position, "$lte": position}, -1
+	}
+
+	if position != entry.Position {
+		_, err := watchlistColl.UpdateMany(context.TODO(),
+			bson.M{"user": entry.UserId, "position": shifted},
+			bson.M{"$inc": bson.M{"position": shift}},
+		)
+		if err != nil {
+			return WatchlistEntry{}, err
+		}
+	}
+
+	entry.Position = position
+	_, err := watchlistColl.UpdateOne(context.TODO(), bson.M{"_id": entry.Id}, bson.M{"$set": bson.M{"position": position, "note": entry.Note}})
+	if err != nil {
+		return WatchlistEntry{}, err
+	}
+
+	return entry, nil
+}
+
+// RemoveFromWatchlist deletes the entry and shifts the next entries to fill its position
+func RemoveFromWatchlist(entry WatchlistEntry) (int64, error) {
+	result, err := watchlistColl.DeleteOne(context.TODO(), bson.M{"_id": entry.Id})
+	if err != nil || result.DeletedCount == 0 {
+		return 0, err
+	}
+
+	_, err = watchlistColl.UpdateMany(context.TODO(),
+		bson.M{"user": entry.UserId, "position": bson.M{"$gt": entry.Position}},
+		bson.M{"$inc": bson.M{"position": -1}},
+	)
+	if err != nil {
+		return 0, err
+	}
+
+	return result.DeletedCount, nil
+}
+
+// FindWatched returns the watched log of a user without the films in the trash, the last watched films first
+func FindWatched(userId string, maxCount int) []WatchedEntry {
+	results := []WatchedEntry{}
+	limit := int64(maxCount)
+	cursor, err := watchedColl.Find(context.TODO(), withoutTrashedFilms(bson.M{"user": userId}), &options.FindOptions{Limit: &limit, Sort: bson.D{{Key: "watched_at", Value: -1}, {Key: "_id", Value: -1}}})
+	if err != nil {
+		panic(err)
+	}
+
+	if err = cursor.All(context.TODO(), &results); err != nil {
+		panic(err)
+	}
+
+	return results
+}
+
+func FindWatchedEntry(filter bson.M) WatchedEntry {
+	var entry WatchedEntry
+	err := watchedColl.FindOne(context.TODO(), filter).Decode(&entry)
+
+	if err == mongo.ErrNoDocuments {
+		return entry
+	}
+	if err != nil {
+		panic(err)
+	}
+
+	return entry
+}
+
+func AddWatchedEntry(entry WatchedEntry) (WatchedEntry, error) {
+	entry.Id = primitive.NewObjectID()
+
+	if _, err := watchedColl.InsertOne(context.TODO(), entry); err != nil {
+		return WatchedEntry{}, err
+	}
+
+	return entry, nil
+}
+
+func ReplaceWatchedEntry(entry WatchedEntry) (int64, error) {
+	result, err := watchedColl.ReplaceOne(context.TODO(), bson.M{"_id": entry.Id}, entry)
+	if err != nil {
+		return 0, err
+	}
+
+	return result.ModifiedCount, nil
+}
+
+// RemoveFilmFromUserLists deletes a film from the watchlists and the watched logs of all the users
+func RemoveFilmFromUserLists(filmId string) error {
+	cursor, err := watchlistColl.Find(context.TODO(), bson.M{"film": filmId})
+	if err != nil {
+		return err
+	}
+	var entries []WatchlistEntry
+	if err = cursor.All(context.TODO(), &entries); err != nil {
+		return err
+	}
+	for _, entry := range entries {
+		if _, err := RemoveFromWatchlist(entry); err != nil {
+			return err
+		}
+	}
+
+	_, err = watchedColl.DeleteMany(context.TODO(), bson.M{"film": filmId})
+	return err
+}
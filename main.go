@@ -1,8 +1,11 @@
 package main
 
 import (
+	"filmflix/cors_policy"
 	"filmflix/db_connection"
 	"filmflix/film_api"
+	"filmflix/rate_limit"
+	"filmflix/request_id"
 	"filmflix/static_serve"
 	"github.com/gin-gonic/gin"
 	"github.com/joho/godotenv"
@@ -16,17 +19,37 @@ func main() {
 	defer db_connection.DisconnectFromDB(dbClient)
 
 	router := gin.Default()
-	router.Use(func(context *gin.Context) {
-		context.Header("Access-Control-Allow-Origin", "*")
-		context.Next()
-	})
+	if err := router.SetTrustedProxies(rate_limit.TrustedProxies()); err != nil {
+		panic(err)
+	}
+	router.Use(request_id.Middleware())
+	router.Use(cors_policy.Middleware(cors_policy.PublicPolicy(), cors_policy.AdminPolicy(), "/api/admin"))
 
 	static_serve.InitStaticRoutes(router)
 
-	apiRoutes := router.Group("/api")
+	apiRoutes := router.Group("/api", rate_limit.Middleware(rate_limit.NewMemoryStore(), film_api.RateLimitKey, rate_limit.ReadLimit(), rate_limit.WriteLimit()), film_api.RequireReadScope)
 	film_api.InitFilmApiRoutes(apiRoutes, dbClient)
 	film_api.InitActorApiRoutes(apiRoutes, dbClient)
 	film_api.InitDirectorApiRoutes(apiRoutes, dbClient)
+	film_api.InitPersonApiRoutes(apiRoutes, dbClient)
+	film_api.InitGenreApiRoutes(apiRoutes, dbClient)
+	film_api.InitStudioApiRoutes(apiRoutes, dbClient)
+	film_api.InitAwardApiRoutes(apiRoutes, dbClient)
+	film_api.InitUserApiRoutes(apiRoutes, dbClient)
+	film_api.InitApiKeyApiRoutes(apiRoutes, dbClient)
+	film_api.InitAuthApiRoutes(apiRoutes, dbClient)
+	film_api.InitAuditApiRoutes(apiRoutes, dbClient)
+	film_api.InitTrashApiRoutes(apiRoutes, dbClient)
+	film_api.InitGraphApiRoutes(apiRoutes)
+	film_api.InitStatsApiRoutes(apiRoutes)
+	film_api.InitAutocompleteApiRoutes(apiRoutes)
+	film_api.InitSearchApiRoutes(apiRoutes)
+	film_api.MigratePeople(dbClient)
+	film_api.BackfillReleaseDates()
+	film_api.BackfillRatings()
+	film_api.BackfillSlugs()
+	film_api.StartTrashPurge()
+
 	err := router.Run(":" + os.Getenv("PORT"))
 	if err != nil {
 		return
@@ -0,0 +1,90 @@
+package rate_limit
+
+import (
+	"github.com/gin-gonic/gin"
+	"math"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// KeyFunc returns the key identifying the client of a request, like its API key, or an empty string if the client
+// is only identified by its IP
+type KeyFunc func(c *gin.Context) string
+
+// Middleware limits the requests of each client with a token bucket, the read requests (GET, HEAD and OPTIONS) and
+// the write requests having their own buckets and limits. It sets the RateLimit-Limit, RateLimit-Remaining and
+// RateLimit-Reset headers, and answers 429 with a Retry-After header when the bucket is empty.
+func Middleware(store Store, keyFunc KeyFunc, read Limit, write Limit) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		key := ""
+		if keyFunc != nil {
+			key = keyFunc(c)
+		}
+		if len(key) == 0 {
+			key = "ip:" + c.ClientIP()
+		}
+
+		limit, kind := write, "write:"
+		if isRead(c.Request.Method) {
+			limit, kind = read, "read:"
+		}
+
+		result := store.Take(kind+key, limit, time.Now())
+
+		c.Header("RateLimit-Limit", strconv.Itoa(result.Limit))
+		c.Header("RateLimit-Remaining", strconv.Itoa(result.Remaining))
+		c.Header("RateLimit-Reset", strconv.Itoa(ceilSeconds(result.Reset)))
+
+		if !result.Allowed {
+			c.Header("Retry-After", strconv.Itoa(ceilSeconds(result.RetryAfter)))
+			c.IndentedJSON(http.StatusTooManyRequests, gin.H{"message": "Too many requests"})
+			c.Abort()
+			return
+		}
+
+		c.Next()
+	}
+}
+
+// ReadLimit returns the limit of the read requests per minute of a client, set with the RATE_LIMIT_READ environment
+// variable and 300 by default
+func ReadLimit() Limit {
+	return Limit{Burst: envInt("RATE_LIMIT_READ", 300), Period: time.Minute}
+}
+
+// WriteLimit returns the limit of the write requests per minute of a client, set with the RATE_LIMIT_WRITE environment
+// variable and 60 by default
+func WriteLimit() Limit {
+	return Limit{Burst: envInt("RATE_LIMIT_WRITE", 60), Period: time.Minute}
+}
+
+// TrustedProxies returns the IPs or CIDR ranges of the proxies whose X-Forwarded-For header gives the IP of the
+// clients, set with the TRUSTED_PROXIES environment variable separated by commas. No proxy is trusted by default, so
+// that a client cannot choose the IP its requests are limited by.
+func TrustedProxies() []string {
+	var proxies []string
+	for _, proxy := range strings.Split(os.Getenv("TRUSTED_PROXIES"), ",") {
+		if proxy = strings.TrimSpace(proxy); len(proxy) > 0 {
+			proxies = append(proxies, proxy)
+		}
+	}
+	return proxies
+}
+
+func envInt(name string, defaultValue int) int {
+	if value, err := strconv.Atoi(os.Getenv(name)); err == nil && value > 0 {
+		return value
+	}
+	return defaultValue
+}
+
+func isRead(method string) bool {
+	return method == http.MethodGet || method == http.MethodHead || method == http.MethodOptions
+}
+
+func ceilSeconds(d time.Duration) int {
+	return int(math.Ceil(d.Seconds()))
+}
@@ -0,0 +1,99 @@
+package rate_limit
+
+import (
+	"math"
+	"sync"
+	"time"
+)
+
+// Limit is the quota of a token bucket: it holds up to Burst tokens, one being taken by each request, and is refilled
+// at the rate of Burst tokens per Period
+type Limit struct {
+	Burst  int
+	Period time.Duration
+}
+
+// Result is the state of a bucket after a request tried to take a token from it
+type Result struct {
+	Allowed   bool
+	Limit     int
+	Remaining int
+	// Reset is the time until the bucket is full again
+	Reset time.Duration
+	// RetryAfter is the time until a token is available, zero if the request was allowed
+	RetryAfter time.Duration
+}
+
+// Store keeps the buckets of the clients, so that they can be shared by several instances of the server
+type Store interface {
+	// Take takes a token from the bucket of the key, after refilling it for the time elapsed since its last use
+	Take(key string, limit Limit, now time.Time) Result
+}
+
+type bucket struct {
+	tokens   float64
+	lastSeen time.Time
+	period   time.Duration
+}
+
+// MemoryStore keeps the buckets in the memory of the server, which is enough for a single instance and for the tests
+type MemoryStore struct {
+	mutex     sync.Mutex
+	buckets   map[string]*bucket
+	lastSweep time.Time
+}
+
+// sweepInterval is the interval between the removals of the buckets which were refilled since their last use
+const sweepInterval = time.Minute
+
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{buckets: make(map[string]*bucket)}
+}
+
+func (store *MemoryStore) Take(key string, limit Limit, now time.Time) Result {
+	store.mutex.Lock()
+	defer store.mutex.Unlock()
+
+	if now.Sub(store.lastSweep) >= sweepInterval {
+		store.sweep(now)
+	}
+
+	burst := float64(limit.Burst)
+	rate := burst / limit.Period.Seconds()
+
+	b, exists := store.buckets[key]
+	if !exists {
+		b = &bucket{tokens: burst}
+		store.buckets[key] = b
+	} else {
+		b.tokens = math.Min(burst, b.tokens+now.Sub(b.lastSeen).Seconds()*rate)
+	}
+	b.lastSeen = now
+	b.period = limit.Period
+
+	result := Result{Limit: limit.Burst}
+	if b.tokens >= 1 {
+		b.tokens--
+		result.Allowed = true
+	} else {
+		result.RetryAfter = seconds((1 - b.tokens) / rate)
+	}
+	result.Remaining = int(b.tokens)
+	result.Reset = seconds((burst - b.tokens) / rate)
+
+	return result
+}
+
+// sweep removes the buckets which are full again, since they are the same as new ones
+func (store *MemoryStore) sweep(now time.Time) {
+	for key, b := range store.buckets {
+		if now.Sub(b.lastSeen) >= b.period {
+			delete(store.buckets, key)
+		}
+	}
+	store.lastSweep = now
+}
+
+func seconds(s float64) time.Duration {
+	return time.Duration(s * float64(time.Second))
+}
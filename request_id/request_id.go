@@ -0,0 +1,58 @@
+package request_id
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"github.com/gin-gonic/gin"
+)
+
+// Header is the header carrying the id of a request, which is given back in the response
+const Header = "X-Request-Id"
+
+// contextKey is the key of the id in the context of the request
+const contextKey = "request_id"
+
+// maxLength is the maximum length of an id given by the client, longer ones being replaced
+const maxLength = 64
+
+// Middleware gives an id to each request, so that its logs and its audit entries can be found. The id given by the
+// client (or by a proxy) in the X-Request-Id header is kept if it is valid.
+func Middleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id := c.GetHeader(Header)
+		if !isValid(id) {
+			id = newId()
+		}
+
+		c.Set(contextKey, id)
+		c.Header(Header, id)
+		c.Next()
+	}
+}
+
+// Get returns the id of the request, or an empty string if the middleware is not used
+func Get(c *gin.Context) string {
+	return c.GetString(contextKey)
+}
+
+func newId() string {
+	random := make([]byte, 16)
+	if _, err := rand.Read(random); err != nil {
+		panic(err)
+	}
+	return hex.EncodeToString(random)
+}
+
+// isValid tells whether an id only contains letters, digits, dashes and underscores, so that it is safe to log
+func isValid(id string) bool {
+	if len(id) == 0 || len(id) > maxLength {
+		return false
+	}
+
+	for _, r := range id {
+		if !(r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || r == '-' || r == '_') {
+			return false
+		}
+	}
+	return true
+}
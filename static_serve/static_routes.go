@@ -1,15 +1,25 @@
 package static_serve
 
 import (
+	"filmflix/asset_storage"
 	"github.com/gin-gonic/gin"
 	"net/http"
+	"os"
+	"path"
+	"path/filepath"
 	"regexp"
 )
 
+// assetsCacheControl lets the clients keep the stored assets for a year, their names change when their content does
+const assetsCacheControl = "public, max-age=31536000, immutable"
+
 func InitStaticRoutes(router *gin.Engine) {
 	router.StaticFile("/favicon.ico", "./www/favicon.ico")
 	router.Use(serveJS)
 	router.Static("/static", "./www/static")
+	assetRoutes := router.Group(asset_storage.URLPrefix())
+	assetRoutes.Use(cacheAssets)
+	assetRoutes.Static("/", asset_storage.LocalDir())
 	router.GET("/", serveApp)
 }
 
@@ -23,3 +33,12 @@ func serveJS(c *gin.Context) {
 	}
 	c.Next()
 }
+
+// cacheAssets lets the clients cache the stored assets, but not the responses to the missing ones
+func cacheAssets(c *gin.Context) {
+	name := filepath.FromSlash(path.Clean("/" + c.Param("filepath")))
+	if info, err := os.Stat(filepath.Join(asset_storage.LocalDir(), name)); err == nil && info.Mode().IsRegular() {
+		c.Writer.Header().Set("Cache-Control", assetsCacheControl)
+	}
+	c.Next()
+}